@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Threshold wraps the check so that it fails when it takes longer than
+// max, even if it eventually succeeds.
+func Threshold(check Check, max time.Duration) Check {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		if err := check(ctx); err != nil {
+			return err
+		}
+		if took := time.Since(start); took > max {
+			return fmt.Errorf("took %v, more than allowed %v", took, max)
+		}
+		return nil
+	}
+}
+
+// Optional wraps the check of a dependency the service can work without,
+// so that its failures are reported as degraded.
+func Optional(check Check) Check {
+	return func(ctx context.Context) error {
+		return Degraded(check(ctx))
+	}
+}
+
+// AnyOf wraps checks of replicas of a dependency, so that it fails only
+// when all of them do. Checks run concurrently and the first success is
+// returned without waiting for the others.
+func AnyOf(checks ...Check) Check {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errs := make(chan error, len(checks))
+		for _, check := range checks {
+			go func(check Check) {
+				errs <- check(ctx)
+			}(check)
+		}
+
+		var msgs []string
+		for range checks {
+			err := <-errs
+			if err == nil {
+				return nil
+			}
+			msgs = append(msgs, err.Error())
+		}
+		return fmt.Errorf("all %d checks failed: %s", len(checks), strings.Join(msgs, "; "))
+	}
+}
+
+// DiskSpace fails when the filesystem containing path has less than
+// minFree bytes available.
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return fmt.Errorf("failed to stat filesystem of %s: %v", path, err)
+		}
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minFree {
+			return fmt.Errorf("only %d bytes free on %s, need %d", free, path, minFree)
+		}
+		return nil
+	}
+}
+
+// HTTPGet fails unless a GET request to url responds with 2xx status.
+func HTTPGet(client *http.Client, url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// LivenessHandler reports that the process is up and able to serve HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(rw, "OK")
+	})
+}
+
+// ReadinessHandler runs registered checks and responds with a JSON report,
+// using 503 status code if any of them has failed. Degraded service is
+// still considered ready.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+
+		code := http.StatusOK
+		if report.Status == StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(rw, code, report)
+	})
+}
+
+// StartupHandler fails until all registered checks pass for the first
+// time. After that it always succeeds, leaving further monitoring to
+// liveness and readiness probes.
+func (r *Registry) StartupHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if r.Started() {
+			fmt.Fprint(rw, "OK")
+			return
+		}
+
+		report := r.Run(req.Context())
+		if report.Status == StatusFail {
+			writeJSON(rw, http.StatusServiceUnavailable, report)
+			return
+		}
+		fmt.Fprint(rw, "OK")
+	})
+}
+
+// WriteError responds with the message in a JSON error object, shared by
+// the services so that they report errors the same way.
+func WriteError(rw http.ResponseWriter, code int, msg string) {
+	writeJSON(rw, code, struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
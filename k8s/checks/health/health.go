@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status values reported for single checks and for the whole registry.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// Check verifies a single dependency of the service. It should return
+// as soon as the context is done.
+type Check func(ctx context.Context) error
+
+// Result describes the outcome of a single check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report aggregates results of all registered checks.
+type Report struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry runs registered checks and caches the report for a while, so
+// that frequent probes do not hammer the dependencies.
+type Registry struct {
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	checks  []namedCheck
+	last    *Report
+	started bool
+}
+
+// NewRegistry creates a registry which caches reports for cacheTTL and
+// gives each check at most timeout to finish.
+func NewRegistry(cacheTTL, timeout time.Duration) *Registry {
+	return &Registry{
+		cacheTTL: cacheTTL,
+		timeout:  timeout,
+	}
+}
+
+// Register adds a named check to the registry.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+	r.last = nil
+}
+
+// Run returns the latest report, running all checks again if the cached
+// one is older than the cache TTL. Concurrent callers share a single run.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last != nil && time.Since(r.last.CheckedAt) < r.cacheTTL {
+		return *r.last
+	}
+
+	report := Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Checks:    make([]Result, len(r.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			report.Checks[i] = r.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		switch {
+		case res.Status == StatusFail:
+			report.Status = StatusFail
+		case res.Status == StatusDegraded && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	if report.Status != StatusFail {
+		r.started = true
+	}
+
+	r.last = &report
+	return report
+}
+
+// Started reports whether all checks have passed (or degraded) at least once.
+func (r *Registry) Started() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.started
+}
+
+func (r *Registry) runCheck(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+
+	res := Result{
+		Name:     c.name,
+		Status:   StatusOK,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	if _, ok := err.(degradedError); ok {
+		res.Status = StatusDegraded
+	}
+	return res
+}
+
+type degradedError struct {
+	error
+}
+
+// Degraded marks the error as not fatal: the service still works, but
+// with reduced capabilities, eg. using a fallback instead of a dependency.
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err}
+}
@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		checks map[string]Check
+		status string
+	}{
+		{
+			desc:   "no checks",
+			status: StatusOK,
+		},
+		{
+			desc: "all passing",
+			checks: map[string]Check{
+				"first":  func(context.Context) error { return nil },
+				"second": func(context.Context) error { return nil },
+			},
+			status: StatusOK,
+		},
+		{
+			desc: "one failing",
+			checks: map[string]Check{
+				"first":  func(context.Context) error { return nil },
+				"second": func(context.Context) error { return errors.New("broken") },
+			},
+			status: StatusFail,
+		},
+		{
+			desc: "one degraded",
+			checks: map[string]Check{
+				"first":    func(context.Context) error { return nil },
+				"optional": Optional(func(context.Context) error { return errors.New("broken") }),
+			},
+			status: StatusDegraded,
+		},
+		{
+			desc: "degraded and failing",
+			checks: map[string]Check{
+				"optional": Optional(func(context.Context) error { return errors.New("broken") }),
+				"second":   func(context.Context) error { return errors.New("broken") },
+			},
+			status: StatusFail,
+		},
+		{
+			desc: "timed out",
+			checks: map[string]Check{
+				"slow": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			status: StatusFail,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := NewRegistry(time.Minute, 10*time.Millisecond)
+			for name, check := range tC.checks {
+				r.Register(name, check)
+			}
+
+			report := r.Run(context.Background())
+			if report.Status != tC.status {
+				t.Errorf("Expected status %q, got %q", tC.status, report.Status)
+			}
+			if len(report.Checks) != len(tC.checks) {
+				t.Errorf("Expected %d results, got %d", len(tC.checks), len(report.Checks))
+			}
+			if r.Started() != (tC.status != StatusFail) {
+				t.Errorf("Expected started to be %v", tC.status != StatusFail)
+			}
+		})
+	}
+}
+
+func TestRegistry_RunCached(t *testing.T) {
+	calls := 0
+	r := NewRegistry(time.Minute, time.Second)
+	r.Register("counting", func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		r.Run(context.Background())
+	}
+	if calls != 1 {
+		t.Errorf("Expected check to run once, ran %d times", calls)
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	passing := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("broken") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	testCases := []struct {
+		desc   string
+		checks []Check
+		failed bool
+	}{
+		{desc: "none", checks: nil, failed: true},
+		{desc: "all passing", checks: []Check{passing, passing}, failed: false},
+		{desc: "one passing", checks: []Check{failing, passing, failing}, failed: false},
+		{desc: "passing and hanging", checks: []Check{hanging, passing}, failed: false},
+		{desc: "all failing", checks: []Check{failing, failing}, failed: true},
+		{desc: "failing and hanging", checks: []Check{failing, hanging}, failed: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := AnyOf(tC.checks...)(ctx)
+			if failed := err != nil; failed != tC.failed {
+				t.Errorf("Expected check to fail: %t, got: %v", tC.failed, err)
+			}
+		})
+	}
+}
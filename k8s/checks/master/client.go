@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type workerResponse struct {
+	Result float64 `json:"result,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// workerError is returned when worker responds with non-2xx status code.
+type workerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *workerError) Error() string {
+	return fmt.Sprintf("worker responded with %d: %s", e.StatusCode, e.Message)
+}
+
+// workerClient calls workers, trying the next address whenever one of
+// them cannot be reached or fails with a server error.
+type workerClient struct {
+	addrs   []string
+	client  *http.Client
+	timeout time.Duration
+	next    uint32
+}
+
+func newWorkerClient(addrs []string, timeout time.Duration) *workerClient {
+	return &workerClient{
+		addrs:   addrs,
+		client:  &http.Client{},
+		timeout: timeout,
+	}
+}
+
+// Power asks a worker to raise base to power. Validation errors reported
+// by a worker are returned as *workerError without trying other workers.
+func (c *workerClient) Power(ctx context.Context, requestID, base, power string) (float64, error) {
+	query := url.Values{}
+	query.Set("base", base)
+	query.Set("power", power)
+
+	var err error
+	start := int(atomic.AddUint32(&c.next, 1))
+	for i := range c.addrs {
+		addr := c.addrs[(start+i)%len(c.addrs)]
+
+		var result float64
+		result, err = c.call(ctx, addr+"/work?"+query.Encode(), requestID)
+		if err == nil {
+			return result, nil
+		}
+		if werr, ok := err.(*workerError); ok && werr.StatusCode < http.StatusInternalServerError {
+			return 0, err
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		log.Printf("[%s] Worker %s failed: %v", requestID, addr, err)
+	}
+	return 0, err
+}
+
+func (c *workerClient) call(ctx context.Context, url, requestID string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(requestIDHeader, requestID)
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			errResp.Error = http.StatusText(resp.StatusCode)
+		}
+		return 0, &workerError{StatusCode: resp.StatusCode, Message: errResp.Error}
+	}
+
+	var jsonResp workerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jsonResp); err != nil {
+		return 0, fmt.Errorf("failed to decode power result: %v", err)
+	}
+	return jsonResp.Result, nil
+}
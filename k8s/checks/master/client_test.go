@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
+)
+
+// Worker responding with the given status code, or result on success.
+type fakeWorker struct {
+	code  int
+	delay time.Duration
+	calls int32
+}
+
+func (w *fakeWorker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	atomic.AddInt32(&w.calls, 1)
+	if req.Header.Get(requestIDHeader) != "req-1" {
+		health.WriteError(rw, http.StatusInternalServerError, "missing request ID")
+		return
+	}
+
+	select {
+	case <-time.After(w.delay):
+	case <-req.Context().Done():
+		return
+	}
+	if w.code != http.StatusOK {
+		health.WriteError(rw, w.code, "invalid value for 'base'")
+		return
+	}
+	fmt.Fprintf(rw, `{"base": %s, "power": %s, "result": 4}`, req.FormValue("base"), req.FormValue("power"))
+}
+
+func startWorker(t *testing.T, w *fakeWorker) string {
+	s := httptest.NewServer(w)
+	t.Cleanup(s.Close)
+	return s.URL
+}
+
+// Returns address nothing listens on.
+func deadAddr(t *testing.T) string {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	return s.URL
+}
+
+func TestWorkerClient_Power(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// workers are tried in order, starting from the second one
+		workers []*fakeWorker
+		dead    bool
+		result  float64
+		// code of the workerError expected, or -1 for any other error
+		code int
+		// calls expected to each worker
+		calls []int32
+	}{
+		{
+			desc:    "first succeeds",
+			workers: []*fakeWorker{{code: http.StatusOK}, {code: http.StatusOK}},
+			result:  4,
+			calls:   []int32{0, 1},
+		},
+		{
+			desc:    "retried after server error",
+			workers: []*fakeWorker{{code: http.StatusOK}, {code: http.StatusInternalServerError}},
+			result:  4,
+			calls:   []int32{1, 1},
+		},
+		{
+			desc:    "retried after timeout",
+			workers: []*fakeWorker{{code: http.StatusOK}, {code: http.StatusOK, delay: time.Second}},
+			result:  4,
+			calls:   []int32{1, 1},
+		},
+		{
+			desc:    "retried after unreachable worker",
+			workers: []*fakeWorker{{code: http.StatusOK}, nil},
+			result:  4,
+			calls:   []int32{1, 0},
+		},
+		{
+			desc:    "bad request not retried",
+			workers: []*fakeWorker{{code: http.StatusOK}, {code: http.StatusBadRequest}},
+			code:    http.StatusBadRequest,
+			calls:   []int32{0, 1},
+		},
+		{
+			desc:    "all failing",
+			workers: []*fakeWorker{{code: http.StatusServiceUnavailable}, {code: http.StatusInternalServerError}},
+			code:    http.StatusServiceUnavailable,
+			calls:   []int32{1, 1},
+		},
+		{
+			desc:    "all timed out",
+			workers: []*fakeWorker{{code: http.StatusOK, delay: time.Second}, nil},
+			code:    -1,
+			calls:   []int32{1, 0},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var addrs []string
+			for _, w := range tC.workers {
+				if w == nil {
+					addrs = append(addrs, deadAddr(t))
+				} else {
+					addrs = append(addrs, startWorker(t, w))
+				}
+			}
+			c := newWorkerClient(addrs, 100*time.Millisecond)
+
+			result, err := c.Power(context.Background(), "req-1", "2", "2")
+			switch {
+			case tC.code == 0 && err != nil:
+				t.Fatalf("Failed to calculate power: %v", err)
+			case tC.code == -1:
+				if _, ok := err.(*workerError); ok || err == nil {
+					t.Fatalf("Expected connection error, got %v", err)
+				}
+			case tC.code > 0:
+				werr, ok := err.(*workerError)
+				if !ok || werr.StatusCode != tC.code {
+					t.Fatalf("Expected worker error with status %d, got %v", tC.code, err)
+				}
+			}
+			if result != tC.result {
+				t.Errorf("Expected result %f, got %f", tC.result, result)
+			}
+			for i, w := range tC.workers {
+				if w == nil {
+					continue
+				}
+				if calls := atomic.LoadInt32(&w.calls); calls != tC.calls[i] {
+					t.Errorf("Expected %d calls to worker %d, got %d", tC.calls[i], i, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerClient_PowerCanceled(t *testing.T) {
+	w := &fakeWorker{code: http.StatusOK, delay: time.Second}
+	c := newWorkerClient([]string{startWorker(t, w), startWorker(t, w)}, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Power(ctx, "req-1", "2", "2"); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline to be exceeded, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&w.calls); calls != 1 {
+		t.Errorf("Expected no more workers to be tried, got %d calls", calls)
+	}
+}
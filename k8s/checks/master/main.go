@@ -1,20 +1,23 @@
 package main
 
 import (
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-)
+	"strings"
+	"time"
 
-type workerResponse struct {
-	Result float64 `json:"result,omitempty"`
-}
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
+)
 
 func main() {
 	addr := os.Getenv("ADDR")
-	workerAddr := os.Getenv("WORKER_ADDR")
+	workerAddrs := strings.Split(os.Getenv("WORKER_ADDR"), ",")
+
+	workers := newWorkerClient(workerAddrs, 2*time.Second)
 
 	// Usage:
 	// curl -X GET 'http://localhost:8000/work?base=5&power=2'
@@ -22,24 +25,51 @@ func main() {
 		base := req.FormValue("base")
 		power := req.FormValue("power")
 
-		url := fmt.Sprintf("%s/work?base=%s&power=%s", workerAddr, base, power)
-		resp, err := http.Get(url)
-		if err != nil {
-			log.Printf("Failed to calculate power: %v", err)
-			http.Error(rw, "failed to calculate power", http.StatusInternalServerError)
-			return
+		requestID := req.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
 		}
-		defer resp.Body.Close()
+		rw.Header().Set(requestIDHeader, requestID)
 
-		var jsonResp workerResponse
-		if err := json.NewDecoder(resp.Body).Decode(&jsonResp); err != nil {
-			log.Printf("Failed to decode power result: %v", err)
-			http.Error(rw, "failed to decode power result", http.StatusInternalServerError)
+		result, err := workers.Power(req.Context(), requestID, base, power)
+		if werr, ok := err.(*workerError); ok && werr.StatusCode == http.StatusBadRequest {
+			log.Printf("[%s] Invalid power request: %v", requestID, err)
+			health.WriteError(rw, http.StatusBadRequest, werr.Message)
+			return
+		}
+		if err != nil {
+			log.Printf("[%s] Failed to calculate power: %v", requestID, err)
+			health.WriteError(rw, http.StatusInternalServerError, "failed to calculate power")
 			return
 		}
 
-		log.Printf("base=%s power=%s result=%f", base, power, jsonResp.Result)
-		fmt.Fprint(rw, jsonResp.Result)
+		log.Printf("[%s] base=%s power=%s result=%f", requestID, base, power, result)
+		fmt.Fprint(rw, result)
 	})
+
+	checks := health.NewRegistry(5*time.Second, time.Second)
+	// single worker being down only degrades the master, as requests are
+	// sent to the others, but it can't work without any of them
+	var workerChecks []health.Check
+	for _, workerAddr := range workerAddrs {
+		check := health.HTTPGet(http.DefaultClient, workerAddr+"/checks/liveness")
+		checks.Register("worker "+workerAddr, health.Optional(check))
+		workerChecks = append(workerChecks, check)
+	}
+	checks.Register("workers", health.AnyOf(workerChecks...))
+	checks.Register("disk", health.DiskSpace(os.TempDir(), 10<<20))
+
+	http.Handle("/checks/liveness", health.LivenessHandler())
+	http.Handle("/checks/readiness", checks.ReadinessHandler())
+	http.Handle("/checks/startup", checks.StartupHandler())
+
 	fmt.Println(http.ListenAndServe(addr, nil))
 }
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
+)
+
+const (
+	localCacheSize = 1000
+	cacheTTL       = time.Minute
+
+	minRedisBackoff = 500 * time.Millisecond
+	maxRedisBackoff = 30 * time.Second
+)
+
+// powerCache computes powers, caching results in Redis. When Redis is
+// unavailable it falls back to a local cache and stops contacting Redis
+// until an exponentially growing backoff period passes.
+type powerCache struct {
+	redis  *redis.Client
+	local  *lru
+	flight flightGroup
+	now    func() time.Time
+
+	mu      sync.Mutex
+	down    bool
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newPowerCache(client *redis.Client) *powerCache {
+	return &powerCache{
+		redis: client,
+		local: newLRU(localCacheSize),
+		now:   time.Now,
+	}
+}
+
+// Power returns base raised to power. Concurrent requests for the same
+// values share a single lookup and computation.
+func (c *powerCache) Power(base, power float64) float64 {
+	key := cacheKey(base, power)
+	return c.flight.Do(key, func() float64 {
+		if result, ok := c.get(key); ok {
+			return result
+		}
+
+		log.Printf("Cache miss for base=%f power=%f", base, power)
+		result := math.Pow(base, power)
+		c.set(key, result)
+		return result
+	})
+}
+
+func (c *powerCache) get(key string) (float64, bool) {
+	if c.redisAvailable() {
+		result, err := c.redis.Get(key).Float64()
+		switch err {
+		case nil:
+			c.redisSucceeded()
+			c.local.Add(key, result)
+			return result, true
+		case redis.Nil:
+			c.redisSucceeded()
+			return 0, false
+		default:
+			c.redisFailed(err)
+		}
+	}
+	return c.local.Get(key)
+}
+
+func (c *powerCache) set(key string, value float64) {
+	c.local.Add(key, value)
+	if !c.redisAvailable() {
+		return
+	}
+	if err := c.redis.Set(key, value, cacheTTL).Err(); err != nil {
+		c.redisFailed(err)
+		return
+	}
+	c.redisSucceeded()
+}
+
+// redisAvailable reports whether Redis may be called. Once the backoff
+// passes, only a single call is let through to probe it, and the next one
+// waits for another backoff period unless the probe reports back sooner.
+func (c *powerCache) redisAvailable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.down {
+		return true
+	}
+	now := c.now()
+	if now.Before(c.retryAt) {
+		return false
+	}
+	c.retryAt = now.Add(c.backoff)
+	return true
+}
+
+func (c *powerCache) redisSucceeded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		log.Printf("Reconnected to Redis")
+	}
+	c.down = false
+	c.backoff = 0
+}
+
+func (c *powerCache) redisFailed(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case !c.down:
+		c.backoff = minRedisBackoff
+	case c.backoff < maxRedisBackoff:
+		c.backoff *= 2
+		if c.backoff > maxRedisBackoff {
+			c.backoff = maxRedisBackoff
+		}
+	}
+	c.down = true
+	c.retryAt = c.now().Add(c.backoff)
+
+	log.Printf("Error connecting to Redis, using local cache for %v: %v", c.backoff, err)
+}
+
+// Check reports the cache as degraded while it falls back to local cache.
+func (c *powerCache) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		return health.Degraded(fmt.Errorf("redis unavailable, using local cache until %s", c.retryAt.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+func cacheKey(base, power float64) string {
+	return fmt.Sprintf("%f:%f", base, power)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+// Returns status the cache check is reported with.
+func checkStatus(c *powerCache) string {
+	r := health.NewRegistry(0, time.Second)
+	r.Register("cache", c.Check)
+	return r.Run(context.Background()).Status
+}
+
+func TestPowerCache_Backoff(t *testing.T) {
+	type step struct {
+		// advance moves the clock before the step
+		advance time.Duration
+		// failed or succeeded reports result of a Redis call
+		failed    bool
+		succeeded bool
+
+		available bool
+		status    string
+		backoff   time.Duration
+	}
+
+	testCases := []struct {
+		desc  string
+		steps []step
+	}{
+		{
+			desc: "healthy",
+			steps: []step{
+				{succeeded: true, available: true, status: health.StatusOK},
+			},
+		},
+		{
+			desc: "backs off after failure",
+			steps: []step{
+				{failed: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{advance: minRedisBackoff - time.Millisecond, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{advance: time.Millisecond, available: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+			},
+		},
+		{
+			desc: "single probe after backoff",
+			steps: []step{
+				{failed: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{advance: minRedisBackoff, available: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{status: health.StatusDegraded, backoff: minRedisBackoff},
+				{advance: minRedisBackoff - time.Millisecond, status: health.StatusDegraded, backoff: minRedisBackoff},
+				// probe never reported back
+				{advance: time.Millisecond, available: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+			},
+		},
+		{
+			desc: "backoff grows up to maximum",
+			steps: []step{
+				{failed: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 2 * minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 4 * minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 8 * minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 16 * minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 32 * minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: maxRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: maxRedisBackoff},
+			},
+		},
+		{
+			desc: "recovers after success",
+			steps: []step{
+				{failed: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+				{failed: true, status: health.StatusDegraded, backoff: 2 * minRedisBackoff},
+				{advance: 2 * minRedisBackoff, succeeded: true, available: true, status: health.StatusOK},
+				{failed: true, status: health.StatusDegraded, backoff: minRedisBackoff},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			clk := &clock{now: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)}
+			c := newPowerCache(nil)
+			c.now = clk.Now
+
+			for i, s := range tC.steps {
+				clk.now = clk.now.Add(s.advance)
+				if s.failed {
+					c.redisFailed(errors.New("connection refused"))
+				}
+				if s.succeeded {
+					c.redisSucceeded()
+				}
+
+				if available := c.redisAvailable(); available != s.available {
+					t.Fatalf("Step %d: expected Redis available to be %t", i, s.available)
+				}
+				if status := checkStatus(c); status != s.status {
+					t.Fatalf("Step %d: expected status %q, got %q", i, s.status, status)
+				}
+				if c.backoff != s.backoff {
+					t.Fatalf("Step %d: expected backoff %v, got %v", i, s.backoff, c.backoff)
+				}
+			}
+		})
+	}
+}
+
+func TestPowerCache_RedisDown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	lis.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: lis.Addr().String(), MaxRetries: -1})
+	defer client.Close()
+	c := newPowerCache(client)
+
+	if result := c.Power(2, 3); result != 8 {
+		t.Fatalf("Expected 8, got %f", result)
+	}
+	if status := checkStatus(c); status != health.StatusDegraded {
+		t.Fatalf("Expected cache to be degraded, got %q", status)
+	}
+	if _, ok := c.local.Get(cacheKey(2, 3)); !ok {
+		t.Errorf("Expected result to be cached locally")
+	}
+}
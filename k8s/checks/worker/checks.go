@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
+)
+
+const healthKey = "health:roundtrip"
+
+func redisPing(client *redis.Client) health.Check {
+	return func(ctx context.Context) error {
+		return client.WithContext(ctx).Ping().Err()
+	}
+}
+
+// redisRoundtrip makes sure that values written to the cache can be read
+// back, which a successful ping alone does not guarantee.
+func redisRoundtrip(client *redis.Client) health.Check {
+	return func(ctx context.Context) error {
+		c := client.WithContext(ctx)
+
+		value := time.Now().Format(time.RFC3339Nano)
+		if err := c.Set(healthKey, value, time.Minute).Err(); err != nil {
+			return fmt.Errorf("failed to write: %v", err)
+		}
+
+		got, err := c.Get(healthKey).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read: %v", err)
+		}
+		if got != value {
+			return fmt.Errorf("read %q, expected %q", got, value)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import "sync"
+
+// flightGroup deduplicates concurrent computations of the same key: the
+// first caller does the work, while the others wait for its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val float64
+	// panicked holds the value fn panicked with, which is re-raised in
+	// the waiters as they have no result to return
+	panicked interface{}
+}
+
+func (g *flightGroup) Do(key string, fn func() float64) float64 {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+		return c.val
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// waiters are released even if fn panics
+	defer func() {
+		if r := recover(); r != nil {
+			c.panicked = r
+		}
+		c.wg.Done()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+	}()
+
+	c.val = fn()
+	return c.val
+}
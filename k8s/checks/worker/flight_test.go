@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup_Do(t *testing.T) {
+	var (
+		g       flightGroup
+		calls   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	fn := func() float64 {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 4
+	}
+
+	results := make([]float64, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = g.Do("2:2", fn)
+		}(i)
+	}
+	// let the callers join the first one before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected a single computation, got %d", calls)
+	}
+	for i, result := range results {
+		if result != 4 {
+			t.Errorf("Expected caller %d to get 4, got %f", i, result)
+		}
+	}
+
+	// finished calls are forgotten
+	if result := g.Do("2:2", func() float64 { return 5 }); result != 5 {
+		t.Errorf("Expected key to be computed again, got %f", result)
+	}
+}
+
+func TestFlightGroup_DoPanic(t *testing.T) {
+	var (
+		g       flightGroup
+		started = make(chan struct{})
+		release = make(chan struct{})
+		waiter  = make(chan interface{})
+	)
+
+	go func() {
+		defer func() { recover() }()
+		g.Do("key", func() float64 {
+			close(started)
+			<-release
+			panic("broken")
+		})
+	}()
+	<-started
+	go func() {
+		defer func() { waiter <- recover() }()
+		g.Do("key", func() float64 { return 1 })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	select {
+	case r := <-waiter:
+		// waiter may also have come after the panic and computed the key
+		if r != nil && r != "broken" {
+			t.Errorf("Expected waiter to panic with the same value, got %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected waiter to be released after panic")
+	}
+
+	if result := g.Do("key", func() float64 { return 2 }); result != 2 {
+		t.Errorf("Expected key to be computed again after panic, got %f", result)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// ops are keys to add, or to get when prefixed with '?'
+		ops     []string
+		present []string
+		evicted []string
+	}{
+		{
+			desc:    "within size",
+			ops:     []string{"a", "b", "c"},
+			present: []string{"a", "b", "c"},
+		},
+		{
+			desc:    "oldest evicted",
+			ops:     []string{"a", "b", "c", "d"},
+			present: []string{"b", "c", "d"},
+			evicted: []string{"a"},
+		},
+		{
+			desc:    "get refreshes",
+			ops:     []string{"a", "b", "c", "?a", "d"},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+		{
+			desc:    "add refreshes",
+			ops:     []string{"a", "b", "c", "a", "d", "e"},
+			present: []string{"a", "d", "e"},
+			evicted: []string{"b", "c"},
+		},
+		{
+			desc:    "missing get",
+			ops:     []string{"a", "?x", "b", "c"},
+			present: []string{"a", "b", "c"},
+			evicted: []string{"x"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := newLRU(3)
+			for i, op := range tC.ops {
+				if op[0] == '?' {
+					c.Get(op[1:])
+				} else {
+					c.Add(op, float64(i))
+				}
+			}
+
+			for _, key := range tC.present {
+				if _, ok := c.Get(key); !ok {
+					t.Errorf("Expected %q to be cached", key)
+				}
+			}
+			for _, key := range tC.evicted {
+				if _, ok := c.Get(key); ok {
+					t.Errorf("Expected %q to be evicted", key)
+				}
+			}
+			if len(c.items) != c.ll.Len() || c.ll.Len() > c.size {
+				t.Errorf("Expected at most %d entries, got %d items and %d list elements", c.size, len(c.items), c.ll.Len())
+			}
+		})
+	}
+}
+
+func TestLRU_AddUpdates(t *testing.T) {
+	c := newLRU(2)
+	c.Add("a", 1)
+	c.Add("a", 2)
+
+	if value, ok := c.Get("a"); !ok || value != 2 {
+		t.Errorf("Expected updated value 2, got %f, %t", value, ok)
+	}
+	if c.ll.Len() != 1 {
+		t.Errorf("Expected a single entry, got %d", c.ll.Len())
+	}
+}
@@ -3,13 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/go-redis/redis"
+
+	"github.com/mycodesmells/golang-examples/k8s/checks/health"
 )
 
 func main() {
@@ -19,58 +20,45 @@ func main() {
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: redisAddr,
 	})
+	cache := newPowerCache(redisClient)
 
 	// Usage:
 	// curl -X GET 'http://localhost:8000/work?base=5&power=2'
 	http.HandleFunc("/work", func(rw http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get("X-Request-ID")
+
 		baseStr := req.FormValue("base")
 		base, err := strconv.ParseFloat(baseStr, 64)
 		if err != nil {
-			http.Error(rw, "invalid value for 'base'", http.StatusBadRequest)
+			log.Printf("[%s] Invalid base %q: %v", requestID, baseStr, err)
+			health.WriteError(rw, http.StatusBadRequest, "invalid value for 'base'")
 			return
 		}
 
 		powerStr := req.FormValue("power")
 		power, err := strconv.ParseFloat(powerStr, 64)
 		if err != nil {
-			http.Error(rw, "invalid value for 'power'", http.StatusBadRequest)
+			log.Printf("[%s] Invalid power %q: %v", requestID, powerStr, err)
+			health.WriteError(rw, http.StatusBadRequest, "invalid value for 'power'")
 			return
 		}
 
-		result, err := redisClient.Get(cacheKey(base, power)).Float64()
-		if err != nil {
-			if err != redis.Nil {
-				log.Printf("Error connecting to Redis: %v", err)
-			} else {
-				log.Printf("Cache miss for base=%f power=%f", base, power)
-			}
-			result = math.Pow(base, power)
-		}
+		result := cache.Power(base, power)
 
-		if err := redisClient.Set(cacheKey(base, power), result, time.Minute).Err(); err != nil {
-			log.Printf("Failed to cache result: %v", err)
-		}
-
-		log.Printf("base=%f power=%f result=%f", base, power, result)
+		log.Printf("[%s] base=%f power=%f result=%f", requestID, base, power, result)
+		rw.Header().Set("Content-Type", "application/json")
 		fmt.Fprintf(rw, `{"base": %f, "power": %f, "result": %f}`, base, power, result)
 	})
 
-	// Liveness check
-	http.HandleFunc("/checks/liveness", func(rw http.ResponseWriter, req *http.Request) {
-		fmt.Fprint(rw, "OK")
-	})
-	// Readiness check
-	http.HandleFunc("/checks/readiness", func(rw http.ResponseWriter, req *http.Request) {
-		if err := redisClient.Ping().Err(); err != nil {
-			http.Error(rw, "FAIL", http.StatusInternalServerError)
-			return
-		}
-		fmt.Fprint(rw, "OK")
-	})
+	checks := health.NewRegistry(5*time.Second, time.Second)
+	checks.Register("cache", cache.Check)
+	checks.Register("redis-ping", health.Optional(health.Threshold(redisPing(redisClient), 100*time.Millisecond)))
+	checks.Register("redis-roundtrip", health.Optional(redisRoundtrip(redisClient)))
+	checks.Register("disk", health.DiskSpace(os.TempDir(), 10<<20))
 
-	fmt.Println(http.ListenAndServe(addr, nil))
-}
+	http.Handle("/checks/liveness", health.LivenessHandler())
+	http.Handle("/checks/readiness", checks.ReadinessHandler())
+	http.Handle("/checks/startup", checks.StartupHandler())
 
-func cacheKey(base, power float64) string {
-	return fmt.Sprintf("%f:%f", base, power)
+	fmt.Println(http.ListenAndServe(addr, nil))
 }
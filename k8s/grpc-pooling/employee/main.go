@@ -4,17 +4,26 @@ import (
 	"context"
 	"math"
 	"net"
+	"net/http"
 	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/mycodesmells/golang-examples/k8s/grpc-pooling/metrics"
 	pb "github.com/mycodesmells/golang-examples/k8s/grpc-pooling/proto/message"
 )
 
+const defaultMetricsAddr = ":9000"
+
 func main() {
 	addr := os.Getenv("ADDR")
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -28,9 +37,19 @@ func main() {
 	}
 	worker := employeeServer{WorkerID: hn}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(metrics.UnaryServerInterceptor),
+	)
 	pb.RegisterWorkerServer(server, worker)
 
+	go func() {
+		log.Infof("Metrics listening on %s", metricsAddr)
+		http.Handle("/metrics", promhttp.Handler())
+		if err := http.ListenAndServe(metricsAddr, nil); err != nil {
+			log.Errorf("Metrics server failed: %v", err)
+		}
+	}()
+
 	log.Infof("Worker initialized, workerID = %s", worker.WorkerID)
 	log.Printf("gRPC Listening on %s", lis.Addr().String())
 	err = server.Serve(lis)
@@ -51,6 +70,7 @@ func (eS employeeServer) Work(ctx context.Context, req *pb.JobRequest) (*pb.JobR
 
 	select {
 	case <-time.After(time.Second * 10):
+		jobsProcessed.WithLabelValues(eS.WorkerID).Inc()
 		return &pb.JobResponse{
 			Id:       req.GetId(),
 			WorkerId: eS.WorkerID,
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var jobsProcessed *prometheus.CounterVec
+
+func init() {
+	jobsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "employee",
+		Subsystem: "jobs",
+		Name:      "processed_total",
+		Help:      "Number of jobs processed by this employee",
+	}, []string{"worker"})
+
+	prometheus.MustRegister(jobsProcessed)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker is a simple circuit breaker. It opens after threshold
+// consecutive failures, rejects requests for the cooldown period and then
+// lets a single trial request through to decide whether to close again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent. Every allowed request must
+// be followed by a call to either Success, Failure or Release.
+func (b *breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		// trial request is still in flight
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = stateClosed
+	b.failures = 0
+}
+
+func (b *breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release ends a request which says nothing about the employee, eg. one
+// cancelled by the client. A trial request is given back, so that the next
+// one may try instead.
+func (b *breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+	}
+}
+
+// State returns current state of the breaker and the number of
+// consecutive failures recorded so far.
+func (b *breaker) State() (breakerState, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures
+}
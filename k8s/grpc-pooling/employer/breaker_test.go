@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		// advance moves the clock before the step
+		advance time.Duration
+		// call is one of "allow", "success", "failure" or "release"
+		call string
+		// allowed is the expected result of "allow"
+		allowed bool
+		state   breakerState
+	}
+
+	testCases := []struct {
+		desc  string
+		steps []step
+	}{
+		{
+			desc: "opens after threshold",
+			steps: []step{
+				{call: "failure", state: stateClosed},
+				{call: "failure", state: stateClosed},
+				{call: "allow", allowed: true, state: stateClosed},
+				{call: "failure", state: stateOpen},
+				{call: "allow", allowed: false, state: stateOpen},
+			},
+		},
+		{
+			desc: "success resets failures",
+			steps: []step{
+				{call: "failure", state: stateClosed},
+				{call: "failure", state: stateClosed},
+				{call: "success", state: stateClosed},
+				{call: "failure", state: stateClosed},
+				{call: "failure", state: stateClosed},
+				{call: "allow", allowed: true, state: stateClosed},
+			},
+		},
+		{
+			desc: "rejects during cooldown",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", state: stateOpen},
+				{advance: 9 * time.Second, call: "allow", allowed: false, state: stateOpen},
+			},
+		},
+		{
+			desc: "single trial after cooldown",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", state: stateOpen},
+				{advance: 10 * time.Second, call: "allow", allowed: true, state: stateHalfOpen},
+				{call: "allow", allowed: false, state: stateHalfOpen},
+			},
+		},
+		{
+			desc: "successful trial closes",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", state: stateOpen},
+				{advance: 10 * time.Second, call: "allow", allowed: true, state: stateHalfOpen},
+				{call: "success", state: stateClosed},
+				{call: "allow", allowed: true, state: stateClosed},
+			},
+		},
+		{
+			desc: "failed trial reopens",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", state: stateOpen},
+				{advance: 10 * time.Second, call: "allow", allowed: true, state: stateHalfOpen},
+				{call: "failure", state: stateOpen},
+				{advance: 9 * time.Second, call: "allow", allowed: false, state: stateOpen},
+				{advance: time.Second, call: "allow", allowed: true, state: stateHalfOpen},
+			},
+		},
+		{
+			desc: "released trial lets another through",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", state: stateOpen},
+				{advance: 10 * time.Second, call: "allow", allowed: true, state: stateHalfOpen},
+				{call: "release", state: stateOpen},
+				{call: "allow", allowed: true, state: stateHalfOpen},
+			},
+		},
+		{
+			desc: "release keeps failures",
+			steps: []step{
+				{call: "failure"}, {call: "failure"},
+				{call: "release", state: stateClosed},
+				{call: "failure", state: stateOpen},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := &clock{now: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)}
+			b := newBreaker(3, 10*time.Second)
+			b.now = c.Now
+
+			for i, s := range tC.steps {
+				c.now = c.now.Add(s.advance)
+				switch s.call {
+				case "allow":
+					if allowed := b.Allow(); allowed != s.allowed {
+						t.Fatalf("Step %d: expected allowed to be %t", i, s.allowed)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "release":
+					b.Release()
+				}
+				if state, _ := b.State(); state != s.state {
+					t.Fatalf("Step %d: expected state %v, got %v", i, s.state, state)
+				}
+			}
+		})
+	}
+}
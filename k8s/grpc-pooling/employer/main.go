@@ -1,39 +1,38 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/processout/grpc-go-pool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
 
 	pb "github.com/mycodesmells/golang-examples/k8s/grpc-pooling/proto/message"
 )
 
+// Employees take 10 seconds per job, so requests get a bit more to finish.
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	addr := os.Getenv("ADDR")
 	employeeAddr := os.Getenv("EMPLOYEE_ADDR")
 
-	var factory grpcpool.Factory
-	factory = func() (*grpc.ClientConn, error) {
-		conn, err := grpc.Dial(employeeAddr, grpc.WithInsecure())
-		if err != nil {
-			log.Fatalf("Failed to start gRPC connection: %v", err)
-		}
-		log.Infof("Connected to employee at %s", employeeAddr)
-		return conn, err
-	}
-
-	pool, err := grpcpool.New(factory, 5, 5, time.Second)
+	eps, err := newEndpoints(strings.Split(employeeAddr, ","))
 	if err != nil {
 		log.Fatalf("Failed to create gRPC pool: %v", err)
 	}
+	prometheus.MustRegister(poolCollector{eps})
 
 	http.HandleFunc("/power", func(rw http.ResponseWriter, req *http.Request) {
 		start := time.Now()
@@ -67,25 +66,19 @@ func main() {
 			return
 		}
 
-		ctx := req.Context()
-		conn, err := pool.Get(ctx)
-		defer conn.Close()
-		if err != nil {
-			msg := "failed to connect to worker"
+		workResp, err := eps.Work(req.Context(), &pb.JobRequest{
+			Id:       uuid.NewV4().String(),
+			Base:     float32(base),
+			Exponent: float32(exponent),
+		})
+		if err == errNoEndpoint {
+			msg := "no worker available"
 			l.Errorln(errors.Wrap(err, msg))
 
-			rw.WriteHeader(http.StatusInternalServerError)
+			rw.WriteHeader(http.StatusServiceUnavailable)
 			rw.Write([]byte(msg))
 			return
 		}
-
-		client := pb.NewWorkerClient(conn.ClientConn)
-
-		workResp, err := client.Work(ctx, &pb.JobRequest{
-			Id:       uuid.NewV4().String(),
-			Base:     float32(base),
-			Exponent: float32(exponent),
-		})
 		if err != nil {
 			msg := "failed to compute result"
 			l.Errorln(errors.Wrap(err, msg))
@@ -104,10 +97,35 @@ func main() {
 		diff := end.Sub(start)
 		log.Infof("Finished in time: %v", diff)
 	})
+	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/debug/pool", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(rw).Encode(eps.Stats()); err != nil {
+			log.Errorln(errors.Wrap(err, "failed to encode pool stats"))
+		}
+	})
 	http.HandleFunc("/healthz", func(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(http.StatusOK)
 	})
 
-	log.Infof("HTTP server listening on %s", addr)
-	http.ListenAndServe(addr, nil)
+	server := &http.Server{Addr: addr}
+	go func() {
+		log.Infof("HTTP server listening on %s", addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	// Waiting for signal to shutdown.
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+
+	// let requests in progress finish before closing their connections
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Errorf("Failed to shut down HTTP server: %v", err)
+	}
+	eps.Close()
 }
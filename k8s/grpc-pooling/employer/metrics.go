@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolWaitSeconds *prometheus.HistogramVec
+	jobsCompleted   *prometheus.CounterVec
+
+	poolAvailableDesc = prometheus.NewDesc(
+		"employer_pool_available",
+		"Number of idle connections in the pool",
+		[]string{"endpoint"}, nil,
+	)
+	poolInUseDesc = prometheus.NewDesc(
+		"employer_pool_in_use",
+		"Number of connections taken from the pool",
+		[]string{"endpoint"}, nil,
+	)
+)
+
+func init() {
+	poolWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "employer",
+		Subsystem: "pool",
+		Name:      "wait_seconds",
+		Help:      "Time spent waiting for a connection from the pool",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"endpoint"})
+
+	jobsCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "employer",
+		Subsystem: "jobs",
+		Name:      "completed_total",
+		Help:      "Number of jobs completed by employees, by the employee which responded",
+	}, []string{"worker"})
+
+	prometheus.MustRegister(poolWaitSeconds, jobsCompleted)
+}
+
+// poolCollector exposes pool gauges read from the endpoints on each scrape.
+type poolCollector struct {
+	eps *endpoints
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAvailableDesc
+	ch <- poolInUseDesc
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.eps.Stats() {
+		ch <- prometheus.MustNewConstMetric(poolAvailableDesc, prometheus.GaugeValue, float64(s.Available), s.Addr)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(s.InUse), s.Addr)
+	}
+}
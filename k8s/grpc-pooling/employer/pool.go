@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/processout/grpc-go-pool"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mycodesmells/golang-examples/k8s/grpc-pooling/metrics"
+	pb "github.com/mycodesmells/golang-examples/k8s/grpc-pooling/proto/message"
+)
+
+const (
+	poolInit        = 5
+	poolCapacity    = 5
+	poolIdleTimeout = time.Second
+
+	breakerThreshold = 5
+	breakerCooldown  = 10 * time.Second
+
+	maxAttempts    = 3
+	initialBackoff = 100 * time.Millisecond
+)
+
+var errNoEndpoint = errors.New("all employee circuits are open")
+
+// endpoint holds a pool of connections to a single employee address,
+// guarded by its own circuit breaker.
+type endpoint struct {
+	addr    string
+	pool    *grpcpool.Pool
+	breaker *breaker
+
+	// discarded counts connections marked as unhealthy, which are closed
+	// instead of being returned to the pool.
+	discarded uint64
+}
+
+func newEndpoint(addr string) (*endpoint, error) {
+	factory := func() (*grpc.ClientConn, error) {
+		conn, err := grpc.Dial(addr,
+			grpc.WithInsecure(),
+			grpc.WithUnaryInterceptor(metrics.UnaryClientInterceptor),
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to start gRPC connection to %s", addr)
+		}
+		log.Infof("Connected to employee at %s", addr)
+		return conn, nil
+	}
+
+	pool, err := grpcpool.New(factory, poolInit, poolCapacity, poolIdleTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create gRPC pool for %s", addr)
+	}
+
+	return &endpoint{
+		addr:    addr,
+		pool:    pool,
+		breaker: newBreaker(breakerThreshold, breakerCooldown),
+	}, nil
+}
+
+// work sends a single job to the employee. Connections which turn out to
+// be unavailable are marked unhealthy so that the pool drops them.
+func (e *endpoint) work(ctx context.Context, req *pb.JobRequest) (*pb.JobResponse, error) {
+	start := time.Now()
+	conn, err := e.pool.Get(ctx)
+	poolWaitSeconds.WithLabelValues(e.addr).Observe(time.Since(start).Seconds())
+	if err != nil {
+		// reported with gRPC code, so that the job is retried elsewhere
+		code := codes.Unavailable
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			code = codes.DeadlineExceeded
+		case context.Canceled:
+			code = codes.Canceled
+		}
+		if code == codes.Unavailable {
+			e.breaker.Failure()
+		} else {
+			// caller gave up waiting, employee might be fine
+			e.breaker.Release()
+		}
+		return nil, status.Errorf(code, "failed to get connection to %s: %v", e.addr, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewWorkerClient(conn.ClientConn)
+	resp, err := client.Work(ctx, req)
+
+	switch status.Code(err) {
+	case codes.OK:
+		e.breaker.Success()
+		jobsCompleted.WithLabelValues(resp.GetWorkerId()).Inc()
+	case codes.Unavailable:
+		conn.Unhealthy()
+		atomic.AddUint64(&e.discarded, 1)
+		e.breaker.Failure()
+	case codes.DeadlineExceeded:
+		e.breaker.Failure()
+	case codes.Canceled:
+		// caller gave up, employee might still be working on it
+		e.breaker.Release()
+	default:
+		// employee responded, so it is reachable
+		e.breaker.Success()
+	}
+	return resp, err
+}
+
+type endpointStats struct {
+	Addr      string `json:"addr"`
+	Capacity  int    `json:"capacity"`
+	Available int    `json:"available"`
+	InUse     int    `json:"in_use"`
+	Discarded uint64 `json:"discarded"`
+	Circuit   string `json:"circuit"`
+	Failures  int    `json:"failures"`
+}
+
+func (e *endpoint) stats() endpointStats {
+	state, failures := e.breaker.State()
+	capacity := e.pool.Capacity()
+	available := e.pool.Available()
+
+	return endpointStats{
+		Addr:      e.addr,
+		Capacity:  capacity,
+		Available: available,
+		InUse:     capacity - available,
+		Discarded: atomic.LoadUint64(&e.discarded),
+		Circuit:   state.String(),
+		Failures:  failures,
+	}
+}
+
+// endpoints balances jobs between employees in a round-robin fashion,
+// skipping those whose circuit is open.
+type endpoints struct {
+	list []*endpoint
+	next uint32
+}
+
+func newEndpoints(addrs []string) (*endpoints, error) {
+	eps := &endpoints{}
+	for _, addr := range addrs {
+		ep, err := newEndpoint(addr)
+		if err != nil {
+			eps.Close()
+			return nil, err
+		}
+		eps.list = append(eps.list, ep)
+	}
+	return eps, nil
+}
+
+func (e *endpoints) pick() (*endpoint, error) {
+	start := int(atomic.AddUint32(&e.next, 1))
+	for i := range e.list {
+		ep := e.list[(start+i)%len(e.list)]
+		if ep.breaker.Allow() {
+			return ep, nil
+		}
+	}
+	return nil, errNoEndpoint
+}
+
+// Work sends the job to one of the employees, retrying with exponential
+// backoff when the employee is unavailable. Jobs are pure computations,
+// so it is safe to send the same request more than once.
+func (e *endpoints) Work(ctx context.Context, req *pb.JobRequest) (*pb.JobResponse, error) {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		ep, err := e.pick()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := ep.work(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt == maxAttempts || status.Code(err) != codes.Unavailable {
+			return nil, err
+		}
+
+		log.Warnf("Job %s failed on %s (attempt %d), retrying in %v: %v", req.GetId(), ep.addr, attempt, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (e *endpoints) Stats() []endpointStats {
+	stats := make([]endpointStats, 0, len(e.list))
+	for _, ep := range e.list {
+		stats = append(stats, ep.stats())
+	}
+	return stats
+}
+
+func (e *endpoints) Close() {
+	for _, ep := range e.list {
+		ep.pool.Close()
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/mycodesmells/golang-examples/k8s/grpc-pooling/proto/message"
+)
+
+// Employee responding with the given error, or with result otherwise.
+// Hanging one responds only once the job is cancelled.
+type fakeWorker struct {
+	id    string
+	err   error
+	hang  bool
+	calls int32
+}
+
+func (w *fakeWorker) Work(ctx context.Context, req *pb.JobRequest) (*pb.JobResponse, error) {
+	atomic.AddInt32(&w.calls, 1)
+	if w.hang {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return &pb.JobResponse{Id: req.GetId(), WorkerId: w.id, Result: 1}, nil
+}
+
+func startWorker(t *testing.T, w *fakeWorker) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterWorkerServer(s, w)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// Returns address nothing listens on.
+func deadAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	lis.Close()
+	return lis.Addr().String()
+}
+
+func newTestEndpoints(t *testing.T, addrs ...string) *endpoints {
+	eps, err := newEndpoints(addrs)
+	if err != nil {
+		t.Fatalf("Failed to create endpoints: %v", err)
+	}
+	t.Cleanup(eps.Close)
+	return eps
+}
+
+func TestEndpoints_Work(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// workers create endpoints, the first pick is the second one
+		workers func(t *testing.T) []string
+		// prepare is called before sending the job
+		prepare  func(eps *endpoints)
+		workerID string
+		code     codes.Code
+		// failures expected on the first picked endpoint
+		failures int
+	}{
+		{
+			desc: "healthy",
+			workers: func(t *testing.T) []string {
+				return []string{startWorker(t, &fakeWorker{id: "a"}), startWorker(t, &fakeWorker{id: "b"})}
+			},
+			workerID: "b",
+			code:     codes.OK,
+		},
+		{
+			desc: "retried after unavailable employee",
+			workers: func(t *testing.T) []string {
+				return []string{startWorker(t, &fakeWorker{id: "a"}), deadAddr(t)}
+			},
+			workerID: "a",
+			code:     codes.OK,
+			failures: 1,
+		},
+		{
+			desc: "retried after failing to get connection",
+			workers: func(t *testing.T) []string {
+				return []string{startWorker(t, &fakeWorker{id: "a"}), startWorker(t, &fakeWorker{id: "b"})}
+			},
+			prepare: func(eps *endpoints) {
+				eps.list[1].pool.Close()
+			},
+			workerID: "a",
+			code:     codes.OK,
+			failures: 1,
+		},
+		{
+			desc: "not retried after employee error",
+			workers: func(t *testing.T) []string {
+				err := status.Error(codes.InvalidArgument, "invalid job")
+				return []string{startWorker(t, &fakeWorker{id: "a"}), startWorker(t, &fakeWorker{id: "b", err: err})}
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "all unavailable",
+			workers: func(t *testing.T) []string {
+				return []string{deadAddr(t), deadAddr(t)}
+			},
+			code:     codes.Unavailable,
+			failures: 2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			eps := newTestEndpoints(t, tC.workers(t)...)
+			if tC.prepare != nil {
+				tC.prepare(eps)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := eps.Work(ctx, &pb.JobRequest{Id: "job", Base: 2, Exponent: 2})
+			if code := status.Code(err); code != tC.code {
+				t.Fatalf("Expected code %v, got %v", tC.code, err)
+			}
+			if resp.GetWorkerId() != tC.workerID {
+				t.Errorf("Expected job to be done by %q, got %q", tC.workerID, resp.GetWorkerId())
+			}
+			if _, failures := eps.list[1].breaker.State(); failures != tC.failures {
+				t.Errorf("Expected %d failures of first picked employee, got %d", tC.failures, failures)
+			}
+		})
+	}
+}
+
+func TestEndpoints_WorkNotRetried(t *testing.T) {
+	w := &fakeWorker{id: "a", err: status.Error(codes.Internal, "broken")}
+	eps := newTestEndpoints(t, startWorker(t, w))
+
+	_, err := eps.Work(context.Background(), &pb.JobRequest{Id: "job"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected internal error, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&w.calls); calls != 1 {
+		t.Errorf("Expected job to be sent once, got %d", calls)
+	}
+	if state, _ := eps.list[0].breaker.State(); state != stateClosed {
+		t.Errorf("Expected circuit of responding employee to be closed, got %v", state)
+	}
+}
+
+func TestEndpoints_AllCircuitsOpen(t *testing.T) {
+	w := &fakeWorker{id: "a"}
+	eps := newTestEndpoints(t, startWorker(t, w), startWorker(t, w))
+	for _, ep := range eps.list {
+		for i := 0; i < breakerThreshold; i++ {
+			ep.breaker.Failure()
+		}
+	}
+
+	_, err := eps.Work(context.Background(), &pb.JobRequest{Id: "job"})
+	if err != errNoEndpoint {
+		t.Fatalf("Expected %v, got %v", errNoEndpoint, err)
+	}
+	if calls := atomic.LoadInt32(&w.calls); calls != 0 {
+		t.Errorf("Expected no jobs to be sent, got %d", calls)
+	}
+}
+
+func TestEndpoint_WorkCancelled(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		worker *fakeWorker
+		// prepare is called before sending the job
+		prepare func(t *testing.T, ep *endpoint, cancel context.CancelFunc)
+	}{
+		{
+			desc:   "waiting for connection",
+			worker: &fakeWorker{id: "a"},
+			prepare: func(t *testing.T, ep *endpoint, cancel context.CancelFunc) {
+				for i := 0; i < poolCapacity; i++ {
+					conn, err := ep.pool.Get(context.Background())
+					if err != nil {
+						t.Fatalf("Failed to get connection: %v", err)
+					}
+					t.Cleanup(func() { conn.Close() })
+				}
+				cancel()
+			},
+		},
+		{
+			desc:   "during job",
+			worker: &fakeWorker{id: "a", hang: true},
+			prepare: func(t *testing.T, ep *endpoint, cancel context.CancelFunc) {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			eps := newTestEndpoints(t, startWorker(t, tC.worker))
+			ep := eps.list[0]
+
+			// let the job be the trial request of the breaker
+			now := time.Now()
+			ep.breaker.now = func() time.Time { return now }
+			for i := 0; i < breakerThreshold; i++ {
+				ep.breaker.Failure()
+			}
+			now = now.Add(breakerCooldown)
+			if !ep.breaker.Allow() {
+				t.Fatalf("Expected trial request to be allowed")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tC.prepare(t, ep, cancel)
+			_, err := ep.work(ctx, &pb.JobRequest{Id: "job"})
+			if code := status.Code(err); code != codes.Canceled {
+				t.Fatalf("Expected code %v, got %v", codes.Canceled, err)
+			}
+
+			if state, failures := ep.breaker.State(); state != stateOpen || failures != breakerThreshold {
+				t.Errorf("Expected open circuit with %d failures, got %v with %d", breakerThreshold, state, failures)
+			}
+			if !ep.breaker.Allow() {
+				t.Errorf("Expected another trial request to be allowed")
+			}
+		})
+	}
+}
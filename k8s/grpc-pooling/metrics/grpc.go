@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	clientHandlingSeconds *prometheus.HistogramVec
+	clientInFlight        *prometheus.GaugeVec
+
+	serverHandlingSeconds *prometheus.HistogramVec
+	serverInFlight        *prometheus.GaugeVec
+)
+
+func init() {
+	clientHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grpc",
+		Subsystem: "client",
+		Name:      "handling_seconds",
+		Help:      "Latency of RPCs sent by the client",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"method", "code"})
+	clientInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grpc",
+		Subsystem: "client",
+		Name:      "in_flight",
+		Help:      "Number of RPCs sent by the client and not yet finished",
+	}, []string{"method"})
+
+	serverHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grpc",
+		Subsystem: "server",
+		Name:      "handling_seconds",
+		Help:      "Latency of RPCs handled by the server",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"method", "code"})
+	serverInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grpc",
+		Subsystem: "server",
+		Name:      "in_flight",
+		Help:      "Number of RPCs being handled by the server",
+	}, []string{"method"})
+
+	prometheus.MustRegister(
+		clientHandlingSeconds,
+		clientInFlight,
+		serverHandlingSeconds,
+		serverInFlight,
+	)
+}
+
+// UnaryClientInterceptor records latency, status code and in-flight count
+// of every unary RPC sent through the connection.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	inFlight := clientInFlight.WithLabelValues(method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	clientHandlingSeconds.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// UnaryServerInterceptor records latency, status code and in-flight count
+// of every unary RPC handled by the server.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	inFlight := serverInFlight.WithLabelValues(info.FullMethod)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	serverHandlingSeconds.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return resp, err
+}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	mpb "github.com/mycodesmells/golang-examples/nats/messaging/proto"
+)
+
+// ContentTypeProtobuf is the only content type supported so far.
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// DecodeError describes a message which could not be decoded.
+type DecodeError struct {
+	Subject Subject
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode message from '%s': %v", e.Subject.Name, e.Err)
+}
+
+// Encode wraps msg in an envelope, after making sure it is of the type
+// the subject expects.
+func (s Subject) Encode(msg proto.Message) ([]byte, error) {
+	if name := proto.MessageName(msg); name != s.MessageType {
+		return nil, errors.Errorf("cannot publish %s on '%s', expected %s", name, s.Name, s.MessageType)
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal message")
+	}
+	bs, err := proto.Marshal(&mpb.Envelope{
+		ContentType:   ContentTypeProtobuf,
+		MessageType:   s.MessageType,
+		SchemaVersion: s.SchemaVersion,
+		Payload:       payload,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal envelope")
+	}
+	return bs, nil
+}
+
+// Decode unwraps data from the envelope into msg. Messages of a different
+// type, or of a schema version newer than the subject's one, are rejected
+// with *DecodeError.
+func (s Subject) Decode(data []byte, msg proto.Message) error {
+	var env mpb.Envelope
+	if err := proto.Unmarshal(data, &env); err != nil {
+		return s.decodeError(errors.Wrap(err, "invalid envelope"))
+	}
+	if env.ContentType != ContentTypeProtobuf {
+		return s.decodeError(errors.Errorf("unsupported content type '%s'", env.ContentType))
+	}
+	if env.MessageType != proto.MessageName(msg) {
+		return s.decodeError(errors.Errorf("unexpected message type %s", env.MessageType))
+	}
+	if env.SchemaVersion > s.SchemaVersion {
+		return s.decodeError(errors.Errorf("unsupported schema version %d", env.SchemaVersion))
+	}
+	if err := proto.Unmarshal(env.Payload, msg); err != nil {
+		return s.decodeError(errors.Wrap(err, "invalid payload"))
+	}
+	return nil
+}
+
+func (s Subject) decodeError(err error) error {
+	return &DecodeError{Subject: s, Err: err}
+}
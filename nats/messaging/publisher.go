@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Conn is implemented by both NATS and NATS Streaming connections.
+type Conn interface {
+	Publish(subject string, data []byte) error
+}
+
+// Publisher sends messages on a single subject.
+type Publisher struct {
+	conn    Conn
+	subject Subject
+}
+
+func NewPublisher(conn Conn, subject Subject) Publisher {
+	return Publisher{
+		conn:    conn,
+		subject: subject,
+	}
+}
+
+func (p Publisher) Subject() Subject {
+	return p.subject
+}
+
+func (p Publisher) Publish(msg proto.Message) error {
+	bs, err := p.subject.Encode(msg)
+	if err != nil {
+		return err
+	}
+	if err := p.conn.Publish(p.subject.Name, bs); err != nil {
+		return errors.Wrapf(err, "failed to publish message on '%s'", p.subject.Name)
+	}
+	return nil
+}
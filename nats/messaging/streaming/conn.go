@@ -0,0 +1,102 @@
+package streaming
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Backends Conn can be connected to.
+const (
+	BackendSTAN      = "stan"
+	BackendJetStream = "jetstream"
+)
+
+// Msg received from either NATS Streaming channel or JetStream stream.
+type Msg struct {
+	Subject  string
+	Sequence uint64
+	// Timestamp in nanoseconds since epoch.
+	Timestamp int64
+	Data      []byte
+	// Deliveries of the message so far, including this one. Zero when
+	// the backend does not track them.
+	Deliveries int
+
+	ack func() error
+}
+
+// Ack acknowledges the message, so that it is not redelivered.
+func (m *Msg) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack()
+}
+
+type MsgHandler func(msg *Msg)
+
+// PubAck confirms that published message has been stored. NATS Streaming
+// does not report sequences of published messages, so only GUID is set.
+type PubAck struct {
+	GUID     string
+	Stream   string
+	Sequence uint64
+}
+
+// PubAckHandler is called once the message is stored, or publishing fails.
+type PubAckHandler func(ack PubAck, err error)
+
+// SubscriptionOptions common for both backends.
+type SubscriptionOptions struct {
+	Start StartPosition
+	// Durable subscriptions (or consumers) keep their position between
+	// restarts of the subscriber.
+	DurableName string
+	// Subscribers in the same queue group split messages between them.
+	QueueGroup string
+	// In manual ack mode messages are redelivered after AckWait unless
+	// acknowledged with Msg.Ack.
+	ManualAcks bool
+	AckWait    time.Duration
+}
+
+type Subscription interface {
+	// Close stops receiving messages, keeping durable subscriptions.
+	Close() error
+}
+
+// Conn is implemented by both NATS Streaming and JetStream connections,
+// so that services can switch between them with configuration only.
+type Conn interface {
+	Publish(subject string, data []byte) error
+	// PublishAsync returns GUID of the message right away, without
+	// waiting for it to be stored.
+	PublishAsync(subject string, data []byte, handler PubAckHandler) (string, error)
+	Subscribe(subject string, handler MsgHandler, opts SubscriptionOptions) (Subscription, error)
+	// EnsureStream makes sure messages published on subjects are stored.
+	// NATS Streaming creates channels on demand, so it is a no-op there.
+	EnsureStream(name string, subjects ...string) error
+	Close() error
+}
+
+// Config of connection to either backend.
+type Config struct {
+	Backend string
+	URL     string
+	// ClusterID is used by NATS Streaming only.
+	ClusterID string
+	ClientID  string
+}
+
+// Connect connects to the configured backend.
+func Connect(cfg Config) (Conn, error) {
+	switch cfg.Backend {
+	case BackendSTAN:
+		return ConnectSTAN(cfg.URL, cfg.ClusterID, cfg.ClientID)
+	case BackendJetStream:
+		return ConnectJetStream(cfg.URL, cfg.ClientID)
+	default:
+		return nil, errors.Errorf("unknown backend '%s', expected '%s' or '%s'", cfg.Backend, BackendSTAN, BackendJetStream)
+	}
+}
@@ -0,0 +1,134 @@
+package streaming
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	mpb "github.com/mycodesmells/golang-examples/nats/messaging/proto"
+)
+
+// DeadLetterQueue counts deliveries of messages which failed to be
+// processed and moves them to a dead letter channel once they reach the
+// limit. Unless the backend reports deliveries itself, counters are kept
+// in memory, so they start over when the subscriber restarts.
+type DeadLetterQueue struct {
+	publisher     messaging.Publisher
+	maxDeliveries int
+
+	mu         sync.Mutex
+	deliveries map[uint64]int
+}
+
+// NewDeadLetterQueue creates queue publishing to the dead letter channel
+// of subject.
+func NewDeadLetterQueue(conn messaging.Conn, subject messaging.Subject, maxDeliveries int) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		publisher:     messaging.NewPublisher(conn, subject.DeadLetter()),
+		maxDeliveries: maxDeliveries,
+		deliveries:    make(map[uint64]int),
+	}
+}
+
+// Failed records failed delivery of the message. Once the message has been
+// delivered too many times, it is moved to the dead letter channel and true
+// is returned, meaning the message should be acknowledged.
+func (q *DeadLetterQueue) Failed(streamMsg *Msg, reason error) bool {
+	deliveries := streamMsg.Deliveries
+	if deliveries == 0 {
+		q.mu.Lock()
+		q.deliveries[streamMsg.Sequence]++
+		deliveries = q.deliveries[streamMsg.Sequence]
+		q.mu.Unlock()
+	}
+
+	if deliveries < q.maxDeliveries {
+		log.Warnf("Message #%d from '%s' failed (delivery %d of %d): %v", streamMsg.Sequence, streamMsg.Subject, deliveries, q.maxDeliveries, reason)
+		return false
+	}
+	return q.Send(streamMsg, deliveries, reason)
+}
+
+// Send moves the message to the dead letter channel right away. It reports
+// whether the message has been moved.
+func (q *DeadLetterQueue) Send(streamMsg *Msg, deliveries int, reason error) bool {
+	letter := &mpb.DeadLetter{
+		Subject:    streamMsg.Subject,
+		Sequence:   streamMsg.Sequence,
+		Timestamp:  streamMsg.Timestamp,
+		Deliveries: uint32(deliveries),
+		Reason:     reason.Error(),
+		Data:       streamMsg.Data,
+		FailedAt:   time.Now().UnixNano(),
+	}
+	if err := q.publisher.Publish(letter); err != nil {
+		// keep the message, so that it is delivered again
+		log.Errorf("Failed to move message #%d to '%s': %v", streamMsg.Sequence, q.publisher.Subject(), err)
+		return false
+	}
+
+	log.Errorf("Moved message #%d from '%s' to '%s' after %d deliveries: %v", streamMsg.Sequence, streamMsg.Subject, q.publisher.Subject(), deliveries, reason)
+	q.Done(streamMsg.Sequence)
+	return true
+}
+
+// Done forgets about failed deliveries of the message.
+func (q *DeadLetterQueue) Done(sequence uint64) {
+	q.mu.Lock()
+	delete(q.deliveries, sequence)
+	q.mu.Unlock()
+}
+
+// permanentError is a failure which redelivery won't fix.
+type permanentError struct {
+	error
+}
+
+// Permanent marks err returned by a handler as one which redelivery won't
+// fix, eg. invalid message, so that the message is moved to the dead
+// letter queue immediately.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// AckHandler returns handler for subscriptions in manual ack mode. Messages
+// are acknowledged once handled successfully or moved to the dead letter
+// queue, otherwise they are redelivered after the subscription's AckWait.
+// Messages which cannot be decoded or fail permanently are moved to the
+// queue immediately.
+func AckHandler(s messaging.Subscriber, q *DeadLetterQueue, handle func(streamMsg *Msg, msg proto.Message) error) MsgHandler {
+	return func(streamMsg *Msg) {
+		msg, err := s.Decode(streamMsg.Data)
+		if err != nil {
+			if q.Send(streamMsg, 1, err) {
+				ack(streamMsg)
+			}
+			return
+		}
+
+		if err := handle(streamMsg, msg); err != nil {
+			moved := false
+			if perm, ok := err.(permanentError); ok {
+				moved = q.Send(streamMsg, 1, perm.error)
+			} else {
+				moved = q.Failed(streamMsg, err)
+			}
+			if moved {
+				ack(streamMsg)
+			}
+			return
+		}
+
+		q.Done(streamMsg.Sequence)
+		ack(streamMsg)
+	}
+}
+
+func ack(streamMsg *Msg) {
+	if err := streamMsg.Ack(); err != nil {
+		log.Errorf("Failed to acknowledge message #%d: %v", streamMsg.Sequence, err)
+	}
+}
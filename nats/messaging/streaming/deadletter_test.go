@@ -0,0 +1,202 @@
+package streaming
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	mpb "github.com/mycodesmells/golang-examples/nats/messaging/proto"
+)
+
+// Subject of messages handled in tests, carrying dead letters for the
+// lack of other message types in this package.
+var testSubject = messaging.Subject{
+	Name:          "letters",
+	MessageType:   "mycodesmells.golangexamples.nats.messaging.proto.DeadLetter",
+	SchemaVersion: 1,
+}
+
+// Keeps published messages, failing to publish while err is set.
+type fakeConn struct {
+	published map[string][][]byte
+	err       error
+}
+
+func (c *fakeConn) Publish(subject string, data []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.published == nil {
+		c.published = make(map[string][][]byte)
+	}
+	c.published[subject] = append(c.published[subject], data)
+	return nil
+}
+
+// Returns dead letters published on the dead letter channel.
+func (c *fakeConn) letters(t *testing.T) []*mpb.DeadLetter {
+	var letters []*mpb.DeadLetter
+	for _, data := range c.published[testSubject.DeadLetter().Name] {
+		var letter mpb.DeadLetter
+		if err := testSubject.DeadLetter().Decode(data, &letter); err != nil {
+			t.Fatalf("Failed to decode dead letter: %v", err)
+		}
+		letters = append(letters, &letter)
+	}
+	return letters
+}
+
+// Returns message with given sequence, counting its acknowledgements.
+func testMsg(t *testing.T, seq uint64, deliveries int, acks *int) *Msg {
+	data, err := testSubject.Encode(&mpb.DeadLetter{Reason: "test"})
+	if err != nil {
+		t.Fatalf("Failed to encode message: %v", err)
+	}
+	return &Msg{
+		Subject:    testSubject.Name,
+		Sequence:   seq,
+		Data:       data,
+		Deliveries: deliveries,
+		ack: func() error {
+			*acks++
+			return nil
+		},
+	}
+}
+
+func TestDeadLetterQueue_Failed(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// deliveries reported by the backend for each delivery, zero when
+		// they are counted by the queue
+		deliveries []int
+		moved      []bool
+		// deliveries recorded in the dead letter
+		recorded uint32
+	}{
+		{
+			desc:       "counted in memory",
+			deliveries: []int{0, 0, 0},
+			moved:      []bool{false, false, true},
+			recorded:   3,
+		},
+		{
+			desc:       "reported by backend",
+			deliveries: []int{1, 2, 3},
+			moved:      []bool{false, false, true},
+			recorded:   3,
+		},
+		{
+			desc:       "redelivered after restart",
+			deliveries: []int{4},
+			moved:      []bool{true},
+			recorded:   4,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			conn := &fakeConn{}
+			q := NewDeadLetterQueue(conn, testSubject, 3)
+
+			var acks int
+			for i, deliveries := range tC.deliveries {
+				if moved := q.Failed(testMsg(t, 7, deliveries, &acks), errors.New("broken")); moved != tC.moved[i] {
+					t.Fatalf("Delivery %d: expected moved to be %t", i+1, tC.moved[i])
+				}
+			}
+
+			letters := conn.letters(t)
+			if len(letters) != 1 {
+				t.Fatalf("Expected a single dead letter, got %d", len(letters))
+			}
+			l := letters[0]
+			if l.Subject != testSubject.Name || l.Sequence != 7 || l.Reason != "broken" || l.Deliveries != tC.recorded {
+				t.Errorf("Unexpected dead letter: %+v", l)
+			}
+			if len(q.deliveries) != 0 {
+				t.Errorf("Expected deliveries to be forgotten, got %v", q.deliveries)
+			}
+		})
+	}
+}
+
+func TestDeadLetterQueue_SendFailure(t *testing.T) {
+	conn := &fakeConn{err: errors.New("disconnected")}
+	q := NewDeadLetterQueue(conn, testSubject, 1)
+
+	var acks int
+	if q.Failed(testMsg(t, 1, 0, &acks), errors.New("broken")) {
+		t.Fatal("Expected message to be kept when dead letter can't be published")
+	}
+
+	conn.err = nil
+	if !q.Failed(testMsg(t, 1, 0, &acks), errors.New("broken")) {
+		t.Fatal("Expected message to be moved on the next delivery")
+	}
+	if letters := conn.letters(t); len(letters) != 1 || letters[0].Deliveries != 2 {
+		t.Errorf("Expected dead letter after 2 deliveries, got %v", letters)
+	}
+}
+
+func TestAckHandler(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// handler fails while failures are left
+		failures int
+		// permanent makes the failures permanent
+		permanent bool
+		// data replaces encoded message
+		data       []byte
+		deliveries int
+		acks       int
+		letters    int
+	}{
+		{desc: "handled", failures: 0, deliveries: 1, acks: 1},
+		{desc: "handled after retry", failures: 1, deliveries: 2, acks: 1},
+		{desc: "moved after retries", failures: 2, deliveries: 2, acks: 1, letters: 1},
+		{desc: "still failing", failures: 3, deliveries: 1, acks: 0},
+		{desc: "undecodable", data: []byte("garbage"), deliveries: 1, acks: 1, letters: 1},
+		{desc: "failed permanently", failures: 1, permanent: true, deliveries: 1, acks: 1, letters: 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			conn := &fakeConn{}
+			q := NewDeadLetterQueue(conn, testSubject, 2)
+			s := messaging.Subscriber{
+				Subject:       testSubject,
+				New:           func() proto.Message { return &mpb.DeadLetter{} },
+				OnDecodeError: func(*messaging.DecodeError, []byte) {},
+			}
+
+			failures := tC.failures
+			handler := AckHandler(s, q, func(*Msg, proto.Message) error {
+				if failures > 0 {
+					failures--
+					if tC.permanent {
+						return Permanent(errors.New("invalid"))
+					}
+					return errors.New("broken")
+				}
+				return nil
+			})
+
+			var acks int
+			for i := 0; i < tC.deliveries; i++ {
+				msg := testMsg(t, 1, 0, &acks)
+				if tC.data != nil {
+					msg.Data = tC.data
+				}
+				handler(msg)
+			}
+
+			if acks != tC.acks {
+				t.Errorf("Expected %d acks, got %d", tC.acks, acks)
+			}
+			if letters := conn.letters(t); len(letters) != tC.letters {
+				t.Errorf("Expected %d dead letters, got %d", tC.letters, len(letters))
+			}
+		})
+	}
+}
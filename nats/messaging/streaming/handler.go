@@ -0,0 +1,22 @@
+// Package streaming adapts messaging subscribers to persistent streams,
+// backed by either NATS Streaming or JetStream.
+package streaming
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+)
+
+// Handler returns stream handler passing decoded messages to handle.
+// Messages which cannot be decoded are only passed to the decode error
+// handler of the subscriber.
+func Handler(s messaging.Subscriber, handle func(streamMsg *Msg, msg proto.Message)) MsgHandler {
+	return func(streamMsg *Msg) {
+		msg, err := s.Decode(streamMsg.Data)
+		if err != nil {
+			return
+		}
+		handle(streamMsg, msg)
+	}
+}
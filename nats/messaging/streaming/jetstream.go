@@ -0,0 +1,263 @@
+package streaming
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type jetStreamConn struct {
+	nc *nats.Conn
+	js nats.JetStreamContext
+}
+
+// ConnectJetStream connects to NATS server with JetStream enabled.
+func ConnectJetStream(url, clientID string) (Conn, error) {
+	nc, err := nats.Connect(url, nats.Name(clientID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to NATS at '%s'", url)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, errors.Wrap(err, "failed to get JetStream context")
+	}
+	return jetStreamConn{nc: nc, js: js}, nil
+}
+
+// Publish waits for the message to be stored in the stream.
+func (c jetStreamConn) Publish(subject string, data []byte) error {
+	_, err := c.js.Publish(subject, data)
+	return err
+}
+
+// How long to wait for acknowledgement of asynchronously published
+// message, the same as NATS Streaming client does by default.
+const pubAckWait = 30 * time.Second
+
+// PublishAsync generates GUID on the client, as NATS Streaming does. It
+// only identifies the message in acknowledgement and logs, as each call
+// publishes a new message.
+func (c jetStreamConn) PublishAsync(subject string, data []byte, handler PubAckHandler) (string, error) {
+	guid := nuid.Next()
+	future, err := c.js.PublishAsync(subject, data)
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		select {
+		case ack := <-future.Ok():
+			handler(PubAck{GUID: guid, Stream: ack.Stream, Sequence: ack.Sequence}, nil)
+		case err := <-future.Err():
+			handler(PubAck{GUID: guid}, err)
+		case <-time.After(pubAckWait):
+			handler(PubAck{GUID: guid}, nats.ErrTimeout)
+		}
+	}()
+	return guid, nil
+}
+
+// Subscribe creates durable consumers up front, so that their start
+// position is only used for the first subscription, just like with NATS
+// Streaming. Sequences are the ones of the whole stream.
+func (c jetStreamConn) Subscribe(subject string, handler MsgHandler, opts SubscriptionOptions) (Subscription, error) {
+	cb := func(natsMsg *nats.Msg) {
+		msg := &Msg{
+			Subject: natsMsg.Subject,
+			Data:    natsMsg.Data,
+			ack:     func() error { return natsMsg.Ack() },
+		}
+		if meta, err := natsMsg.Metadata(); err == nil {
+			msg.Sequence = meta.Sequence.Stream
+			msg.Timestamp = meta.Timestamp.UnixNano()
+			msg.Deliveries = int(meta.NumDelivered)
+		}
+		handler(msg)
+	}
+
+	var subOpts []nats.SubOpt
+	if opts.DurableName != "" {
+		stream, err := c.ensureConsumer(subject, opts)
+		if err != nil {
+			return nil, err
+		}
+		subOpts = append(subOpts, nats.Bind(stream, opts.DurableName))
+	} else {
+		subOpts = append(subOpts, jetStreamStartOption(opts.Start))
+		if opts.AckWait > 0 {
+			subOpts = append(subOpts, nats.AckWait(opts.AckWait))
+		}
+	}
+	if opts.ManualAcks {
+		subOpts = append(subOpts, nats.ManualAck())
+	}
+
+	var sub *nats.Subscription
+	var err error
+	if opts.QueueGroup == "" {
+		sub, err = c.js.Subscribe(subject, cb, subOpts...)
+	} else {
+		sub, err = c.js.QueueSubscribe(subject, opts.QueueGroup, cb, subOpts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jetStreamSubscription{sub}, nil
+}
+
+// Creates durable consumer, unless it already exists. Returns the name of
+// the stream storing subject.
+func (c jetStreamConn) ensureConsumer(subject string, opts SubscriptionOptions) (string, error) {
+	stream, err := c.streamBySubject(subject)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find stream of '%s'", subject)
+	}
+
+	_, err = c.js.ConsumerInfo(stream, opts.DurableName)
+	if err == nil {
+		return stream, nil
+	}
+	if err != nats.ErrConsumerNotFound {
+		return "", errors.Wrapf(err, "failed to get consumer '%s'", opts.DurableName)
+	}
+
+	cfg := &nats.ConsumerConfig{
+		Durable:        opts.DurableName,
+		DeliverSubject: nats.NewInbox(),
+		DeliverGroup:   opts.QueueGroup,
+		FilterSubject:  subject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		AckWait:        opts.AckWait,
+	}
+	switch opts.Start.Mode {
+	case StartLastReceived:
+		cfg.DeliverPolicy = nats.DeliverLastPolicy
+	case StartAll:
+		cfg.DeliverPolicy = nats.DeliverAllPolicy
+	case StartAtSequence:
+		cfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		cfg.OptStartSeq = opts.Start.Sequence
+	case StartAtTime, StartAtTimeDelta:
+		t := opts.Start.Time
+		if opts.Start.Mode == StartAtTimeDelta {
+			t = time.Now().Add(-opts.Start.Delta)
+		}
+		cfg.DeliverPolicy = nats.DeliverByStartTimePolicy
+		cfg.OptStartTime = &t
+	default:
+		cfg.DeliverPolicy = nats.DeliverNewPolicy
+	}
+	if _, err := c.js.AddConsumer(stream, cfg); err != nil {
+		return "", errors.Wrapf(err, "failed to create consumer '%s'", opts.DurableName)
+	}
+	log.Infof("Created consumer '%s' on stream '%s'", opts.DurableName, stream)
+	return stream, nil
+}
+
+// Name of the stream storing messages published on subject.
+func (c jetStreamConn) streamBySubject(subject string) (string, error) {
+	req, err := json.Marshal(map[string]string{"subject": subject})
+	if err != nil {
+		return "", err
+	}
+	reply, err := c.nc.Request("$JS.API.STREAM.NAMES", req, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Streams []string `json:"streams"`
+		Error   *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply.Data, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		return "", errors.New(resp.Error.Description)
+	}
+	if len(resp.Streams) != 1 {
+		return "", errors.Errorf("expected one stream, found %d", len(resp.Streams))
+	}
+	return resp.Streams[0], nil
+}
+
+// EnsureStream creates the stream or updates its subjects.
+func (c jetStreamConn) EnsureStream(name string, subjects ...string) error {
+	info, err := c.js.StreamInfo(name)
+	if err == nats.ErrStreamNotFound {
+		_, err = c.js.AddStream(&nats.StreamConfig{
+			Name:     name,
+			Subjects: subjects,
+			Storage:  nats.FileStorage,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create stream '%s'", name)
+		}
+		log.Infof("Created stream '%s' for %v", name, subjects)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get stream '%s'", name)
+	}
+
+	if sameSubjects(info.Config.Subjects, subjects) {
+		return nil
+	}
+	cfg := info.Config
+	cfg.Subjects = subjects
+	if _, err := c.js.UpdateStream(&cfg); err != nil {
+		return errors.Wrapf(err, "failed to update stream '%s'", name)
+	}
+	log.Infof("Updated subjects of stream '%s' to %v", name, subjects)
+	return nil
+}
+
+func (c jetStreamConn) Close() error {
+	c.nc.Close()
+	return nil
+}
+
+func jetStreamStartOption(pos StartPosition) nats.SubOpt {
+	switch pos.Mode {
+	case StartLastReceived:
+		return nats.DeliverLast()
+	case StartAll:
+		return nats.DeliverAll()
+	case StartAtSequence:
+		return nats.StartSequence(pos.Sequence)
+	case StartAtTime:
+		return nats.StartTime(pos.Time)
+	case StartAtTimeDelta:
+		return nats.StartTime(time.Now().Add(-pos.Delta))
+	default:
+		return nats.DeliverNew()
+	}
+}
+
+type jetStreamSubscription struct {
+	sub *nats.Subscription
+}
+
+// Close drains the subscription without deleting its durable consumer.
+func (s jetStreamSubscription) Close() error {
+	return s.sub.Drain()
+}
+
+func sameSubjects(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
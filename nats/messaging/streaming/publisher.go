@@ -0,0 +1,63 @@
+package streaming
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+)
+
+// ErrTooManyInFlight is returned when too many published messages are
+// still waiting for acknowledgement.
+var ErrTooManyInFlight = errors.New("too many messages waiting for acknowledgement")
+
+// AsyncPublisher sends messages on a single subject without waiting for
+// them to be stored, limiting how many of them can be in flight.
+type AsyncPublisher struct {
+	conn     Conn
+	subject  messaging.Subject
+	inFlight chan struct{}
+}
+
+func NewAsyncPublisher(conn Conn, subject messaging.Subject, maxInFlight int) *AsyncPublisher {
+	return &AsyncPublisher{
+		conn:     conn,
+		subject:  subject,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+}
+
+func (p *AsyncPublisher) Subject() messaging.Subject {
+	return p.subject
+}
+
+// Publish returns GUID of the message, while its acknowledgement is passed
+// to handler later on. Once the window of messages in flight is full, it
+// fails right away with ErrTooManyInFlight.
+func (p *AsyncPublisher) Publish(msg proto.Message, handler PubAckHandler) (string, error) {
+	bs, err := p.subject.Encode(msg)
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case p.inFlight <- struct{}{}:
+	default:
+		return "", ErrTooManyInFlight
+	}
+
+	guid, err := p.conn.PublishAsync(p.subject.Name, bs, func(ack PubAck, err error) {
+		<-p.inFlight
+		handler(ack, err)
+	})
+	if err != nil {
+		<-p.inFlight
+		return "", errors.Wrapf(err, "failed to publish message on '%s'", p.subject.Name)
+	}
+	return guid, nil
+}
+
+// InFlight returns number of messages waiting for acknowledgement.
+func (p *AsyncPublisher) InFlight() int {
+	return len(p.inFlight)
+}
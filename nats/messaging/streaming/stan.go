@@ -0,0 +1,83 @@
+package streaming
+
+import (
+	stan "github.com/nats-io/go-nats-streaming"
+	stanpb "github.com/nats-io/go-nats-streaming/pb"
+	"github.com/pkg/errors"
+)
+
+type stanConn struct {
+	conn stan.Conn
+}
+
+// ConnectSTAN connects to NATS Streaming server.
+func ConnectSTAN(url, clusterID, clientID string) (Conn, error) {
+	conn, err := stan.Connect(clusterID, clientID, stan.NatsURL(url))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to NATS Streaming at '%s'", url)
+	}
+	return stanConn{conn: conn}, nil
+}
+
+func (c stanConn) Publish(subject string, data []byte) error {
+	return c.conn.Publish(subject, data)
+}
+
+func (c stanConn) PublishAsync(subject string, data []byte, handler PubAckHandler) (string, error) {
+	return c.conn.PublishAsync(subject, data, func(guid string, err error) {
+		handler(PubAck{GUID: guid}, err)
+	})
+}
+
+func (c stanConn) Subscribe(subject string, handler MsgHandler, opts SubscriptionOptions) (Subscription, error) {
+	stanOpts := []stan.SubscriptionOption{stanStartOption(opts.Start)}
+	if opts.DurableName != "" {
+		stanOpts = append(stanOpts, stan.DurableName(opts.DurableName))
+	}
+	if opts.ManualAcks {
+		stanOpts = append(stanOpts, stan.SetManualAckMode())
+	}
+	if opts.AckWait > 0 {
+		stanOpts = append(stanOpts, stan.AckWait(opts.AckWait))
+	}
+
+	cb := func(stanMsg *stan.Msg) {
+		handler(&Msg{
+			Subject:   stanMsg.Subject,
+			Sequence:  stanMsg.Sequence,
+			Timestamp: stanMsg.Timestamp,
+			Data:      stanMsg.Data,
+			ack:       stanMsg.Ack,
+		})
+	}
+
+	if opts.QueueGroup == "" {
+		return c.conn.Subscribe(subject, cb, stanOpts...)
+	}
+	return c.conn.QueueSubscribe(subject, opts.QueueGroup, cb, stanOpts...)
+}
+
+func (c stanConn) EnsureStream(name string, subjects ...string) error {
+	return nil
+}
+
+func (c stanConn) Close() error {
+	return c.conn.Close()
+}
+
+func stanStartOption(pos StartPosition) stan.SubscriptionOption {
+	switch pos.Mode {
+	case StartLastReceived:
+		return stan.StartWithLastReceived()
+	case StartAll:
+		return stan.DeliverAllAvailable()
+	case StartAtSequence:
+		return stan.StartAtSequence(pos.Sequence)
+	case StartAtTime:
+		return stan.StartAtTime(pos.Time)
+	case StartAtTimeDelta:
+		return stan.StartAtTimeDelta(pos.Delta)
+	default:
+		return stan.StartAt(stanpb.StartPosition_NewOnly)
+	}
+}
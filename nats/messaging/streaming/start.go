@@ -0,0 +1,90 @@
+package streaming
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StartMode selects which messages a new subscription receives first.
+type StartMode int
+
+const (
+	StartNew StartMode = iota
+	StartLastReceived
+	StartAll
+	StartAtSequence
+	StartAtTime
+	StartAtTimeDelta
+)
+
+// StartPosition of a subscription. Durable subscriptions use it only when
+// created, later they resume from the last acknowledged message.
+type StartPosition struct {
+	Mode     StartMode
+	Sequence uint64
+	Time     time.Time
+	Delta    time.Duration
+}
+
+const startUsage = "expected one of: ONLY_NEW, MOST_RECENT, ALL, SEQUENCE:<seq>, TIME:<RFC3339 time>, TIME_DELTA:<duration>"
+
+var startExamples = map[string]string{
+	"SEQUENCE":   "SEQUENCE:42",
+	"TIME":       "TIME:2018-06-01T12:00:00Z",
+	"TIME_DELTA": "TIME_DELTA:1h30m",
+}
+
+// ParseStartPosition parses position as given in configuration, eg.
+// SEQUENCE:42, TIME:2018-06-01T12:00:00Z or TIME_DELTA:1h30m.
+func ParseStartPosition(s string) (StartPosition, error) {
+	mode, value := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		mode, value = s[:i], s[i+1:]
+	}
+
+	switch mode {
+	case "ONLY_NEW", "MOST_RECENT", "ALL":
+		if value != "" {
+			return StartPosition{}, errors.Errorf("%s takes no value", mode)
+		}
+	case "SEQUENCE", "TIME", "TIME_DELTA":
+		if value == "" {
+			return StartPosition{}, errors.Errorf("%s requires a value, eg. %s", mode, startExamples[mode])
+		}
+	default:
+		return StartPosition{}, errors.Errorf("unknown start option '%s', %s", s, startUsage)
+	}
+
+	switch mode {
+	case "MOST_RECENT":
+		return StartPosition{Mode: StartLastReceived}, nil
+	case "ALL":
+		return StartPosition{Mode: StartAll}, nil
+	case "SEQUENCE":
+		seq, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || seq == 0 {
+			return StartPosition{}, errors.Errorf("invalid sequence '%s', expected a positive number", value)
+		}
+		return StartPosition{Mode: StartAtSequence, Sequence: seq}, nil
+	case "TIME":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return StartPosition{}, errors.Errorf("invalid time '%s', expected RFC3339 format, eg. %s", value, startExamples[mode])
+		}
+		if t.After(time.Now()) {
+			return StartPosition{}, errors.Errorf("start time %s is in the future", value)
+		}
+		return StartPosition{Mode: StartAtTime, Time: t}, nil
+	case "TIME_DELTA":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return StartPosition{}, errors.Errorf("invalid time delta '%s', expected a positive duration, eg. %s", value, startExamples[mode])
+		}
+		return StartPosition{Mode: StartAtTimeDelta, Delta: d}, nil
+	default:
+		return StartPosition{Mode: StartNew}, nil
+	}
+}
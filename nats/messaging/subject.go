@@ -0,0 +1,54 @@
+// Package messaging provides typed publishers and subscribers of protobuf
+// messages sent over NATS and NATS Streaming.
+//
+// NATS messages carry no headers, so every message is wrapped in an
+// envelope describing its content type, message type and schema version.
+package messaging
+
+// Subject is a NATS subject (or NATS Streaming channel) together with the
+// type and schema version of messages published on it.
+type Subject struct {
+	Name          string
+	MessageType   string
+	SchemaVersion uint32
+}
+
+// WithName returns a subject carrying the same messages under another
+// name, eg. a reply inbox.
+func (s Subject) WithName(name string) Subject {
+	s.Name = name
+	return s
+}
+
+// DeadLetter returns subject where messages from s which could not be
+// processed are moved to.
+func (s Subject) DeadLetter() Subject {
+	return Subject{
+		Name:          s.Name + ".dlq",
+		MessageType:   "mycodesmells.golangexamples.nats.messaging.proto.DeadLetter",
+		SchemaVersion: 1,
+	}
+}
+
+func (s Subject) String() string {
+	return s.Name
+}
+
+// Subjects used by the services.
+var (
+	PublishPost = Subject{
+		Name:          "posts:publish",
+		MessageType:   "mycodesmells.golangexamples.nats.pubsub.proto.PublishPostMessage",
+		SchemaVersion: 1,
+	}
+	PostPublished = Subject{
+		Name:          "posts:published",
+		MessageType:   "mycodesmells.golangexamples.nats.pubsub.proto.PublishPostResult",
+		SchemaVersion: 1,
+	}
+	PublishEpisode = Subject{
+		Name:          "episodes:publish",
+		MessageType:   "mycodesmells.golangexamples.nats.streaming.proto.PublishEpisodeMessage",
+		SchemaVersion: 1,
+	}
+)
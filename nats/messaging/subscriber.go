@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"github.com/golang/protobuf/proto"
+	nats "github.com/nats-io/go-nats"
+	log "github.com/sirupsen/logrus"
+)
+
+// DecodeErrorHandler is notified about every message which could not be
+// decoded, eg. to count or store them for inspection.
+type DecodeErrorHandler func(err *DecodeError, data []byte)
+
+// LogDecodeError is the default DecodeErrorHandler.
+func LogDecodeError(err *DecodeError, data []byte) {
+	log.Errorf("Dropping message (%d bytes): %v", len(data), err)
+}
+
+// Subscriber decodes messages received on a subject.
+type Subscriber struct {
+	Subject Subject
+	// New returns an empty message to decode data into.
+	New func() proto.Message
+	// OnDecodeError defaults to LogDecodeError.
+	OnDecodeError DecodeErrorHandler
+}
+
+// Decode returns message decoded from data. Errors are passed to the
+// decode error handler before being returned.
+func (s Subscriber) Decode(data []byte) (proto.Message, error) {
+	msg := s.New()
+	if err := s.Subject.Decode(data, msg); err != nil {
+		onErr := s.OnDecodeError
+		if onErr == nil {
+			onErr = LogDecodeError
+		}
+		if decErr, ok := err.(*DecodeError); ok {
+			onErr(decErr, data)
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Handler returns NATS handler passing decoded messages to handle. Messages
+// which cannot be decoded are only passed to the decode error handler.
+func (s Subscriber) Handler(handle func(natsMsg *nats.Msg, msg proto.Message)) nats.MsgHandler {
+	return func(natsMsg *nats.Msg) {
+		msg, err := s.Decode(natsMsg.Data)
+		if err != nil {
+			return
+		}
+		handle(natsMsg, msg)
+	}
+}
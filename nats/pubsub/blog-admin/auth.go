@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Credentials accepted by requireAuth. Either list of API keys, basic auth
+// user and password, or both can be configured.
+type credentials struct {
+	APIKeys  []string
+	User     string
+	Password string
+}
+
+func (c credentials) Empty() bool {
+	return len(c.APIKeys) == 0 && c.User == ""
+}
+
+// Checks API key, sent either as a bearer token or in X-API-Key header,
+// or basic auth credentials of the request.
+func (c credentials) Valid(req *http.Request) bool {
+	key := req.Header.Get("X-API-Key")
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key != "" {
+		for _, k := range c.APIKeys {
+			if secureCompare(key, k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	user, password, ok := req.BasicAuth()
+	if !ok || c.User == "" {
+		return false
+	}
+	// evaluate both, so that timing does not reveal which one is wrong
+	userOK := secureCompare(user, c.User)
+	passwordOK := secureCompare(password, c.Password)
+	return userOK && passwordOK
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Middleware rejecting requests without valid credentials.
+func requireAuth(creds credentials, next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		if !creds.Valid(req) {
+			if creds.User != "" {
+				rw.Header().Set("WWW-Authenticate", `Basic realm="blog-admin"`)
+			}
+			writeError(rw, http.StatusUnauthorized, "Invalid or missing credentials")
+			return
+		}
+		next(rw, req)
+	}
+}
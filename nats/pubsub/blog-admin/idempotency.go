@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Outcome of reserving an idempotency key.
+type reservation int
+
+const (
+	// key has not been seen before and now belongs to the caller
+	reservationNew reservation = iota
+	// key was already used with the same request
+	reservationReplay
+	// key was already used with a different request
+	reservationConflict
+)
+
+type idempotencyEntry struct {
+	fingerprint [sha256.Size]byte
+	postID      string
+	createdAt   time.Time
+}
+
+// idempotencyKeys remembers recently used Idempotency-Key headers, so that
+// retried submissions are not published again.
+type idempotencyKeys struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	keys map[string]idempotencyEntry
+}
+
+func newIdempotencyKeys(ttl time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:  ttl,
+		keys: make(map[string]idempotencyEntry),
+	}
+}
+
+// Reserve records the key for the request body and post ID. If the key is
+// already known, ID of the post it was used for is returned instead.
+func (k *idempotencyKeys) Reserve(key string, body []byte, postID string) (reservation, string) {
+	fingerprint := sha256.Sum256(body)
+	now := time.Now()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.expire(now)
+	if e, ok := k.keys[key]; ok {
+		if e.fingerprint != fingerprint {
+			return reservationConflict, e.postID
+		}
+		return reservationReplay, e.postID
+	}
+
+	k.keys[key] = idempotencyEntry{
+		fingerprint: fingerprint,
+		postID:      postID,
+		createdAt:   now,
+	}
+	return reservationNew, postID
+}
+
+// Release forgets the key, so that the request can be retried after it
+// failed to be published.
+func (k *idempotencyKeys) Release(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.keys, key)
+}
+
+func (k *idempotencyKeys) expire(now time.Time) {
+	for key, e := range k.keys {
+		if now.Sub(e.createdAt) > k.ttl {
+			delete(k.keys, key)
+		}
+	}
+}
@@ -2,16 +2,29 @@ package main
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/mux"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/nats-io/go-nats"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
 )
 
 type config struct {
 	Addr     string `envconfig:"ADDR" default:":8000"`
 	NatsAddr string `envconfig:"NATS_ADDR" default:"nats://localhost:4222"`
+
+	// Publishing requires one of API keys or basic auth credentials.
+	APIKeys           []string `envconfig:"API_KEYS"`
+	BasicAuthUser     string   `envconfig:"BASIC_AUTH_USER"`
+	BasicAuthPassword string   `envconfig:"BASIC_AUTH_PASSWORD"`
+
+	MaxRequestSize int64         `envconfig:"MAX_REQUEST_SIZE" default:"1048576"`
+	IdempotencyTTL time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
 }
 
 func main() {
@@ -21,6 +34,15 @@ func main() {
 		log.Fatalf("Failed to load configuration from env: %v", err)
 	}
 
+	creds := credentials{
+		APIKeys:  cfg.APIKeys,
+		User:     cfg.BasicAuthUser,
+		Password: cfg.BasicAuthPassword,
+	}
+	if creds.Empty() {
+		log.Fatal("Either API_KEYS or BASIC_AUTH_USER and BASIC_AUTH_PASSWORD must be set")
+	}
+
 	// Connect to NATS
 	natsClient, err := nats.Connect(cfg.NatsAddr)
 	if err != nil {
@@ -28,12 +50,26 @@ func main() {
 	}
 
 	srv := server{
-		natsClient: natsClient,
+		natsClient:     natsClient,
+		posts:          messaging.NewPublisher(natsClient, messaging.PublishPost),
+		publications:   newPublications(),
+		idempotency:    newIdempotencyKeys(cfg.IdempotencyTTL),
+		maxRequestSize: cfg.MaxRequestSize,
+	}
+
+	// Track results of post generation
+	results := messaging.Subscriber{
+		Subject: messaging.PostPublished,
+		New:     func() proto.Message { return &pb.PublishPostResult{} },
+	}
+	if _, err := natsClient.Subscribe(results.Subject.Name, results.Handler(srv.publications.HandleResult)); err != nil {
+		log.Fatalf("Failed to start subscription on '%s': %v", results.Subject, err)
 	}
 
 	// Serve HTTP
 	r := mux.NewRouter()
-	r.HandleFunc("/publish", srv.HandlePublishPost)
+	r.HandleFunc("/publish", requireAuth(creds, srv.HandlePublishPost)).Methods(http.MethodPost)
+	r.HandleFunc("/posts/{slug}/status", srv.HandlePostStatus).Methods(http.MethodGet)
 
 	log.Infof("Starting HTTP server on '%s'", cfg.Addr)
 	if err := http.ListenAndServe(cfg.Addr, r); err != nil {
@@ -3,62 +3,203 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	nats "github.com/nats-io/go-nats"
+	"github.com/nats-io/nuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mycodesmells/golang-examples/nats/messaging"
 	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
+	"github.com/mycodesmells/golang-examples/nats/pubsub/slug"
 )
 
 type publishRequest struct {
-	Title   string `json:"title,omitempty"`
-	Content string `json:"content,omitempty"`
+	ID        string   `json:"id,omitempty"`
+	Operation string   `json:"operation,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Content   string   `json:"content,omitempty"`
+	Author    string   `json:"author,omitempty"`
+	Summary   string   `json:"summary,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
 }
 
-const (
-	topicPublishPost = "posts:publish"
-)
+var operations = map[string]pb.PublishPostMessage_Operation{
+	"":       pb.PublishPostMessage_CREATE,
+	"create": pb.PublishPostMessage_CREATE,
+	"update": pb.PublishPostMessage_UPDATE,
+	"delete": pb.PublishPostMessage_DELETE,
+}
 
 type server struct {
-	natsClient *nats.Conn
+	natsClient     *nats.Conn
+	posts          messaging.Publisher
+	publications   *publications
+	idempotency    *idempotencyKeys
+	maxRequestSize int64
 }
 
 func (s server) HandlePublishPost(rw http.ResponseWriter, req *http.Request) {
-	var pubReq publishRequest
-	if err := json.NewDecoder(req.Body).Decode(&pubReq); err != nil {
+	// read one byte more than allowed to tell if the body was too large
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, s.maxRequestSize+1))
+	if err != nil {
 		log.Errorf("Failed to read request: %v", err)
-		http.Error(rw, "Invalid request", http.StatusBadRequest)
+		writeError(rw, http.StatusBadRequest, "Failed to read request")
+		return
+	}
+	if int64(len(body)) > s.maxRequestSize {
+		writeError(rw, http.StatusRequestEntityTooLarge, "Request is too large")
+		return
+	}
+
+	var pubReq publishRequest
+	if err := json.Unmarshal(body, &pubReq); err != nil {
+		log.Errorf("Failed to decode request: %v", err)
+		writeError(rw, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
+	op, ok := operations[pubReq.Operation]
+	if !ok {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{
+			Error:  "Invalid request",
+			Fields: map[string]string{"operation": "must be one of: create, update, delete"},
+		})
+		return
+	}
+	if fields := pubReq.Validate(op); fields != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "Invalid request", Fields: fields})
+		return
+	}
+
+	// Wait for the result only if asked to, eg. /publish?wait=5s
+	var wait time.Duration
+	if waitStr := req.URL.Query().Get("wait"); waitStr != "" {
+		if wait, err = time.ParseDuration(waitStr); err != nil || wait < 0 {
+			writeError(rw, http.StatusBadRequest, "Invalid wait duration")
+			return
+		}
+	}
+
 	message := &pb.PublishPostMessage{
-		Title:   pubReq.Title,
-		Content: pubReq.Content,
+		Id:        pubReq.ID,
+		Operation: op,
+		Title:     pubReq.Title,
+		Content:   pubReq.Content,
+		Author:    pubReq.Author,
+		Summary:   pubReq.Summary,
+		Tags:      pubReq.Tags,
+	}
+	if op == pb.PublishPostMessage_CREATE {
+		if message.Id == "" {
+			message.Id = nuid.Next()
+		}
+		message.Slug = slug.Make(pubReq.Title)
+	}
+
+	// Publish the same request only once per idempotency key.
+	key := req.Header.Get("Idempotency-Key")
+	if key != "" {
+		res, postID := s.idempotency.Reserve(key, body, message.Id)
+		switch res {
+		case reservationConflict:
+			writeError(rw, http.StatusUnprocessableEntity, "Idempotency key was already used for a different request")
+			return
+		case reservationReplay:
+			s.replay(rw, postID)
+			return
+		}
 	}
 
-	if err := s.publishMessage(topicPublishPost, message); err != nil {
+	if wait > 0 {
+		err = s.publishAndWait(rw, message, wait)
+	} else {
+		err = s.publish(rw, message)
+	}
+	if err != nil {
 		log.Errorf("Failed to publish message onto queue: %v", err)
-		http.Error(rw, "", http.StatusInternalServerError)
-		return
+		if key != "" {
+			s.idempotency.Release(key)
+		}
+		writeError(rw, http.StatusInternalServerError, "Failed to publish post")
+	}
+}
+
+// Publishes the message and responds right away, leaving the client to
+// check the status later on.
+func (s server) publish(rw http.ResponseWriter, message *pb.PublishPostMessage) error {
+	s.publications.Pending(message.Id, message.Slug)
+	if err := s.publishMessage(message); err != nil {
+		return err
 	}
 
-	log.Printf("Publishing on '%s': '%s'", topicPublishPost, pubReq.Content)
+	log.Printf("Publishing on '%s': '%s'", s.posts.Subject(), message.Id)
+	rw.Header().Set("Location", fmt.Sprintf("/posts/%s/status", message.Id))
 	fmt.Fprint(rw, "Post publication is pending")
+	return nil
 }
 
-func (s server) publishMessage(topic string, msg proto.Message) error {
-	bs, err := proto.Marshal(msg)
+// Publishes the message as a request and responds with generation result.
+// Errors are returned only if no response has been written yet.
+func (s server) publishAndWait(rw http.ResponseWriter, message *pb.PublishPostMessage, wait time.Duration) error {
+	subject := s.posts.Subject()
+	bs, err := subject.Encode(message)
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal proto message")
+		return err
 	}
 
-	if err := s.natsClient.Publish(topicPublishPost, bs); err != nil {
+	s.publications.Pending(message.Id, message.Slug)
+	log.Printf("Publishing on '%s' and waiting %v for result", subject, wait)
+	rw.Header().Set("Location", fmt.Sprintf("/posts/%s/status", message.Id))
+	natsMsg, err := s.natsClient.Request(subject.Name, bs, wait)
+	if err == nats.ErrTimeout {
+		pub, _ := s.publications.Get(message.Id)
+		writeJSON(rw, http.StatusAccepted, pub)
+		return nil
+	}
+	if err != nil {
 		return errors.Wrap(err, "failed to publish message")
 	}
 
+	var result pb.PublishPostResult
+	if err := messaging.PostPublished.Decode(natsMsg.Data, &result); err != nil {
+		// the post has been handled, so it must not be published again
+		log.Errorf("Failed to unmarshal publication result: %v", err)
+		writeError(rw, http.StatusInternalServerError, "Invalid publication result")
+		return nil
+	}
+
+	pub := s.publications.Result(&result)
+	code := http.StatusOK
+	if pub.Status == statusFailed {
+		code = http.StatusInternalServerError
+	}
+	writeJSON(rw, code, pub)
+	return nil
+}
+
+// Responds to a retried request with the current state of the post it
+// was originally published as.
+func (s server) replay(rw http.ResponseWriter, postID string) {
+	pub, ok := s.publications.Get(postID)
+	if !ok {
+		pub = publication{ID: postID, Status: statusPending}
+	}
+	rw.Header().Set("Location", fmt.Sprintf("/posts/%s/status", postID))
+	rw.Header().Set("Idempotent-Replayed", "true")
+	writeJSON(rw, http.StatusOK, pub)
+}
+
+func (s server) publishMessage(msg proto.Message) error {
+	if err := s.posts.Publish(msg); err != nil {
+		return err
+	}
+
 	if err := s.natsClient.Flush(); err != nil {
 		return errors.Wrap(err, "failed to flush message")
 	}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	nats "github.com/nats-io/go-nats"
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
+)
+
+// Publication states.
+const (
+	statusPending   = "pending"
+	statusPublished = "published"
+	statusDeleted   = "deleted"
+	statusFailed    = "failed"
+)
+
+type publication struct {
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug,omitempty"`
+	Status    string    `json:"status"`
+	URL       string    `json:"url,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// publications keeps track of the latest known state of each post.
+type publications struct {
+	mu    sync.RWMutex
+	posts map[string]publication
+	slugs map[string]string
+}
+
+func newPublications() *publications {
+	return &publications{
+		posts: make(map[string]publication),
+		slugs: make(map[string]string),
+	}
+}
+
+func (p *publications) Pending(id, slug string) {
+	p.mu.RLock()
+	pub, ok := p.posts[id]
+	p.mu.RUnlock()
+
+	if !ok {
+		pub = publication{ID: id, Slug: slug}
+	}
+	pub.Status = statusPending
+	pub.Error = ""
+	p.set(pub)
+}
+
+func (p *publications) Result(result *pb.PublishPostResult) publication {
+	pub := publication{
+		ID:     result.GetId(),
+		Slug:   result.GetSlug(),
+		Status: statusPublished,
+		URL:    result.GetUrl(),
+	}
+	switch {
+	case result.GetError() != "":
+		pub.Status = statusFailed
+		pub.Error = result.GetError()
+	case result.GetOperation() == pb.PublishPostMessage_DELETE:
+		pub.Status = statusDeleted
+	}
+	p.set(pub)
+	return pub
+}
+
+// Get finds publication either by post slug or its ID.
+func (p *publications) Get(key string) (publication, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if id, ok := p.slugs[key]; ok {
+		key = id
+	}
+	pub, ok := p.posts[key]
+	return pub, ok
+}
+
+func (p *publications) set(pub publication) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old, ok := p.posts[pub.ID]; ok && old.Slug != pub.Slug {
+		delete(p.slugs, old.Slug)
+	}
+	if pub.Slug != "" {
+		p.slugs[pub.Slug] = pub.ID
+	}
+
+	pub.UpdatedAt = time.Now()
+	p.posts[pub.ID] = pub
+}
+
+// Handler tracking results of post generation.
+func (p *publications) HandleResult(natsMsg *nats.Msg, msg proto.Message) {
+	p.Result(msg.(*pb.PublishPostResult))
+}
+
+// Responds with publication status of a post, identified by slug or ID.
+func (s server) HandlePostStatus(rw http.ResponseWriter, req *http.Request) {
+	slug := mux.Vars(req)["slug"]
+
+	pub, ok := s.publications.Get(slug)
+	if !ok {
+		writeError(rw, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, pub)
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Errorf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
+	"github.com/mycodesmells/golang-examples/nats/pubsub/slug"
+)
+
+const (
+	maxTitleLength   = 200
+	maxSummaryLength = 500
+	maxTags          = 10
+)
+
+// Error response, with details about invalid fields if there are any.
+type errorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func writeError(rw http.ResponseWriter, code int, msg string) {
+	writeJSON(rw, code, errorResponse{Error: msg})
+}
+
+// Returns problems with request fields, keyed by their JSON names, or nil
+// if the request is valid for given operation.
+func (r publishRequest) Validate(op pb.PublishPostMessage_Operation) map[string]string {
+	fields := make(map[string]string)
+
+	if op != pb.PublishPostMessage_CREATE && r.ID == "" {
+		fields["id"] = "is required"
+	}
+	if op == pb.PublishPostMessage_DELETE {
+		return nilIfEmpty(fields)
+	}
+
+	switch {
+	case strings.TrimSpace(r.Title) == "":
+		fields["title"] = "is required"
+	case utf8.RuneCountInString(r.Title) > maxTitleLength:
+		fields["title"] = "is too long"
+	case slug.Make(r.Title) == "":
+		fields["title"] = "must contain at least one latin letter or digit"
+	}
+	if strings.TrimSpace(r.Content) == "" {
+		fields["content"] = "is required"
+	}
+	if utf8.RuneCountInString(r.Summary) > maxSummaryLength {
+		fields["summary"] = "is too long"
+	}
+	if len(r.Tags) > maxTags {
+		fields["tags"] = "too many tags"
+	}
+	for _, t := range r.Tags {
+		if strings.TrimSpace(t) == "" {
+			fields["tags"] = "tags cannot be empty"
+			break
+		}
+	}
+	return nilIfEmpty(fields)
+}
+
+func nilIfEmpty(fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
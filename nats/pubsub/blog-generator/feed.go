@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Link      atomLink `xml:"link"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// Writes Atom feed with all posts into the storage.
+func writeFeed(store storage, name, baseURL string, posts []indexEntry) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	feed := atomFeed{
+		ID:      baseURL + "/",
+		Title:   "Blog",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: baseURL + "/feed.xml", Rel: "self"},
+	}
+	for _, p := range posts {
+		url := fmt.Sprintf("%s/%s.html", baseURL, p.Slug)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        url,
+			Title:     p.Title,
+			Published: p.Published.UTC().Format(time.RFC3339),
+			Updated:   p.Updated.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: url},
+		})
+	}
+
+	buff := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buff)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return errors.Wrap(err, "failed to encode feed")
+	}
+	if err := store.Put(name, buff.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to write feed")
+	}
+	return nil
+}
@@ -1,78 +1,229 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
-	"os"
-	"path/filepath"
-	"strings"
+	"path"
 	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
+	"github.com/mycodesmells/golang-examples/nats/pubsub/slug"
 )
 
 // Data to be rendered into post page template.
 type postPageData struct {
 	Title   string
-	Content string
+	Content template.HTML
 	Date    string
+	Author  string
+	Tags    []string
 }
 
-// URL-friendly version of post title.
-func (d postPageData) Slug() string {
-	t := strings.TrimSpace(d.Title)
-	t = strings.ToLower(t)
-	return strings.Replace(t, " ", "-", -1)
+// Data to be rendered into index page template. Root is the relative
+// path to the top of the blog, used for links to posts.
+type indexPageData struct {
+	Posts []indexEntry
+	Root  string
 }
 
+// Data to be rendered into tag page template.
+type tagPageData struct {
+	Tag   string
+	Posts []indexEntry
+	Root  string
+}
+
+var templateFuncs = template.FuncMap{
+	"slugify": slug.Make,
+}
+
+// Prefix of objects keeping the post index, which are not served.
+const indexPrefix = "posts/"
+
 type pageGenerator struct {
-	basePath string
+	store     storage
+	templates *themeTemplates
+	baseURL   string
+	index     *postIndex
 }
 
-func newPageGenerator(basePath string) (pageGenerator, error) {
-	if err := os.MkdirAll(basePath, os.ModeDir); err != nil {
-		return pageGenerator{}, errors.Wrapf(err, "failed to create '%s' directory", basePath)
+func newPageGenerator(store storage, templates *themeTemplates, baseURL string) (pageGenerator, error) {
+	index, err := loadIndex(store, indexPrefix)
+	if err != nil {
+		return pageGenerator{}, errors.Wrap(err, "failed to load post index")
 	}
 
 	return pageGenerator{
-		basePath: basePath,
+		store:     store,
+		templates: templates,
+		baseURL:   baseURL,
+		index:     index,
 	}, nil
 }
 
-func (g pageGenerator) Generate(pubMsg pb.PublishPostMessage) error {
-	data := postPageData{
-		Title:   pubMsg.Title,
-		Content: pubMsg.Content,
-		Date:    time.Now().Format("01-02-2006"),
+// Generate creates, updates or deletes post page, depending on requested
+// operation, then regenerates the index page and the feed.
+func (g pageGenerator) Generate(pubMsg pb.PublishPostMessage) (indexEntry, error) {
+	// other replicas might have published posts in the meantime
+	if err := g.index.Reload(); err != nil {
+		return indexEntry{ID: pubMsg.Id}, errors.Wrap(err, "failed to reload post index")
 	}
-	fileName := filepath.Join(g.basePath, fmt.Sprintf("%s.html", data.Slug()))
 
-	f, err := os.Create(fileName)
-	if err != nil {
-		return errors.Wrap(err, "failed to create post file")
+	postSlug := pubMsg.Slug
+	if postSlug == "" {
+		postSlug = pubMsg.Title
+	}
+	postSlug = slug.Make(postSlug)
+	id := pubMsg.Id
+	if id == "" {
+		id = postSlug
 	}
 
-	t := template.New("post-page")
-	buff, err := Asset("post-page.tpl")
-	if err != nil {
-		return errors.Wrap(err, "failed to load template")
+	var entry indexEntry
+	var err error
+	switch pubMsg.Operation {
+	case pb.PublishPostMessage_CREATE, pb.PublishPostMessage_UPDATE:
+		entry, err = g.generatePost(id, postSlug, pubMsg)
+	case pb.PublishPostMessage_DELETE:
+		entry, err = g.deletePost(id)
+	default:
+		err = errors.Errorf("unknown operation %v", pubMsg.Operation)
 	}
-	t, err = t.Parse(string(buff))
 	if err != nil {
-		return errors.Wrap(err, "failed to parse template file")
+		return entry, err
 	}
 
-	if err := t.Execute(f, data); err != nil {
-		if rErr := os.Remove(fileName); rErr != nil {
-			log.Errorf("Failed to remove corrupt file: %v", rErr)
+	// include posts generated by other replicas in the meantime; pages
+	// written concurrently may still miss them until the next post
+	if err := g.index.Reload(); err != nil {
+		return entry, errors.Wrap(err, "failed to reload post index")
+	}
+	if err := g.generateIndex(); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (g pageGenerator) generatePost(id, slug string, pubMsg pb.PublishPostMessage) (indexEntry, error) {
+	now := time.Now()
+
+	entry, exists := g.index.Get(id)
+	switch {
+	case exists:
+		// keep the slug, so that links to the post don't break
+		entry.Title = pubMsg.Title
+		entry.Author = pubMsg.Author
+		entry.Summary = pubMsg.Summary
+		entry.Tags = pubMsg.Tags
+		entry.Updated = now
+	case pubMsg.Operation == pb.PublishPostMessage_UPDATE:
+		return indexEntry{ID: id}, errors.Errorf("post '%s' does not exist", id)
+	default:
+		entry = indexEntry{
+			ID:        id,
+			Title:     pubMsg.Title,
+			Slug:      g.index.ResolveSlug(id, slug),
+			Author:    pubMsg.Author,
+			Summary:   pubMsg.Summary,
+			Tags:      pubMsg.Tags,
+			Published: now,
+			Updated:   now,
 		}
+	}
+	if entry.Slug == "" {
+		return entry, errors.New("post slug cannot be empty")
+	}
+
+	data := postPageData{
+		Title:   entry.Title,
+		Content: renderMarkdown(pubMsg.Content),
+		Date:    entry.Date(),
+		Author:  entry.Author,
+		Tags:    entry.Tags,
+	}
+	fileName := fmt.Sprintf("%s.html", entry.Slug)
+	if err := g.render(fileName, "post-page.tpl", data); err != nil {
+		return entry, errors.Wrap(err, "failed to render post page")
+	}
+	log.Infof("Generated post page: '%s'", fileName)
+
+	if err := g.index.Put(entry); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
 
-		return errors.Wrap(err, "failed to render post page")
+func (g pageGenerator) deletePost(id string) (indexEntry, error) {
+	entry, exists := g.index.Get(id)
+	if !exists {
+		return indexEntry{ID: id}, errors.Errorf("post '%s' does not exist", id)
 	}
-	log.Infof("Generated new post page: '%s'", fileName)
 
+	fileName := fmt.Sprintf("%s.html", entry.Slug)
+	if err := g.store.Delete(fileName); err != nil {
+		return entry, errors.Wrap(err, "failed to remove post page")
+	}
+	log.Infof("Removed post page: '%s'", fileName)
+
+	if err := g.index.Delete(id); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (g pageGenerator) generateIndex() error {
+	posts := g.index.ByDate()
+
+	if err := g.render("index.html", "index.tpl", indexPageData{Posts: posts}); err != nil {
+		return errors.Wrap(err, "failed to render index page")
+	}
+
+	if err := writeFeed(g.store, "feed.xml", g.baseURL, posts); err != nil {
+		return errors.Wrap(err, "failed to generate feed")
+	}
+
+	return g.generateTags()
+}
+
+// Generates a page for each tag and removes pages of unused ones.
+func (g pageGenerator) generateTags() error {
+	current := make(map[string]bool)
+	for slug, tag := range g.index.ByTag() {
+		fileName := path.Join("tags", fmt.Sprintf("%s.html", slug))
+		if err := g.render(fileName, "tag.tpl", tagPageData{Tag: tag.Name, Posts: tag.Posts, Root: "../"}); err != nil {
+			return errors.Wrapf(err, "failed to render page of tag '%s'", tag.Name)
+		}
+		current[fileName] = true
+	}
+
+	existing, err := g.store.List("tags/")
+	if err != nil {
+		return errors.Wrap(err, "failed to list tag pages")
+	}
+	for _, fileName := range existing {
+		if current[fileName] {
+			continue
+		}
+		if err := g.store.Delete(fileName); err != nil {
+			return errors.Wrap(err, "failed to remove unused tag page")
+		}
+	}
+	return nil
+}
+
+func (g pageGenerator) render(fileName, page string, data interface{}) error {
+	// render into memory first, so that a failing template does not
+	// replace a page that is already published
+	var out bytes.Buffer
+	if err := g.templates.Execute(&out, page, data); err != nil {
+		return errors.Wrap(err, "failed to execute template")
+	}
+	if err := g.store.Put(fileName, out.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
 	return nil
 }
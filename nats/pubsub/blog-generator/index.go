@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mycodesmells/golang-examples/nats/pubsub/slug"
+)
+
+// Entry describing a single post in the blog index.
+type indexEntry struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Slug      string    `json:"slug"`
+	Author    string    `json:"author,omitempty"`
+	Summary   string    `json:"summary,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Published time.Time `json:"published"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Date of publication, as displayed on pages.
+func (e indexEntry) Date() string {
+	return e.Published.Format("01-02-2006")
+}
+
+// Index of all generated posts. Each entry is persisted as a separate
+// object under the prefix, so that replicas sharing the storage only ever
+// write entries of posts they generate and never drop those of others.
+type postIndex struct {
+	store  storage
+	prefix string
+	posts  map[string]indexEntry
+}
+
+func loadIndex(store storage, prefix string) (*postIndex, error) {
+	idx := &postIndex{
+		store:  store,
+		prefix: prefix,
+	}
+	if err := idx.Reload(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Reload replaces entries with those persisted in the storage, which may
+// have been changed by another replica.
+func (i *postIndex) Reload() error {
+	names, err := i.store.List(i.prefix)
+	if err != nil {
+		return errors.Wrap(err, "failed to list index entries")
+	}
+
+	posts := make(map[string]indexEntry)
+	for _, name := range names {
+		bs, err := i.store.Get(name)
+		if os.IsNotExist(err) {
+			// removed since listing
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read index entry '%s'", name)
+		}
+
+		var e indexEntry
+		if err := json.Unmarshal(bs, &e); err != nil {
+			return errors.Wrapf(err, "failed to decode index entry '%s'", name)
+		}
+		posts[e.ID] = e
+	}
+	i.posts = posts
+	return nil
+}
+
+// Name of the object keeping entry of the post, with ID escaped so that
+// it can't point outside of the prefix.
+func (i *postIndex) entryName(id string) string {
+	return i.prefix + url.PathEscape(id) + ".json"
+}
+
+func (i *postIndex) Get(id string) (indexEntry, bool) {
+	e, ok := i.posts[id]
+	return e, ok
+}
+
+func (i *postIndex) Put(e indexEntry) error {
+	bs, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode index entry")
+	}
+	if err := i.store.Put(i.entryName(e.ID), bs); err != nil {
+		return errors.Wrap(err, "failed to write index entry")
+	}
+	i.posts[e.ID] = e
+	return nil
+}
+
+func (i *postIndex) Delete(id string) error {
+	if err := i.store.Delete(i.entryName(id)); err != nil {
+		return errors.Wrap(err, "failed to remove index entry")
+	}
+	delete(i.posts, id)
+	return nil
+}
+
+// All posts, the most recent first.
+func (i *postIndex) ByDate() []indexEntry {
+	entries := make([]indexEntry, 0, len(i.posts))
+	for _, e := range i.posts {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].Published.Equal(entries[b].Published) {
+			return entries[a].ID < entries[b].ID
+		}
+		return entries[a].Published.After(entries[b].Published)
+	})
+	return entries
+}
+
+// Posts with the same tag, named as in the first of them.
+type tagPosts struct {
+	Name  string
+	Posts []indexEntry
+}
+
+// Posts grouped by slugs of tags, the most recent first. Tags differing
+// only in case or punctuation share a page, while those without any
+// letters or digits are skipped.
+func (i *postIndex) ByTag() map[string]*tagPosts {
+	tags := make(map[string]*tagPosts)
+	for _, e := range i.ByDate() {
+		seen := make(map[string]bool)
+		for _, t := range e.Tags {
+			tagSlug := slug.Make(t)
+			if tagSlug == "" || seen[tagSlug] {
+				continue
+			}
+			seen[tagSlug] = true
+
+			if tags[tagSlug] == nil {
+				tags[tagSlug] = &tagPosts{Name: t}
+			}
+			tags[tagSlug].Posts = append(tags[tagSlug].Posts, e)
+		}
+	}
+	return tags
+}
+
+// Slugs of pages generated next to posts, which posts can't use.
+var reservedSlugs = map[string]bool{
+	"index": true,
+	"feed":  true,
+	"tags":  true,
+	"posts": true,
+}
+
+// Slug for the post with given ID. If another post already uses the
+// requested slug, or it is reserved, a suffix derived from the ID is added,
+// so that the same post always ends up with the same slug.
+func (i *postIndex) ResolveSlug(id, slug string) string {
+	taken := reservedSlugs[slug]
+	for _, e := range i.posts {
+		if e.Slug == slug && e.ID != id {
+			taken = true
+		}
+	}
+	if !taken {
+		return slug
+	}
+	sum := sha1.Sum([]byte(id))
+	return slug + "-" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newTestStorage(t *testing.T) localStorage {
+	dir, err := ioutil.TempDir("", "blog-generator")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	store, err := newLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	return store
+}
+
+func TestPostIndex_Replicas(t *testing.T) {
+	store := newTestStorage(t)
+	if err := store.Put("index.html", []byte("<html></html>")); err != nil {
+		t.Fatalf("Failed to write page: %v", err)
+	}
+
+	// both replicas load the index before any of them writes to it
+	first, err := loadIndex(store, indexPrefix)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	second, err := loadIndex(store, indexPrefix)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if err := first.Put(indexEntry{ID: "first", Slug: "first"}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if err := second.Put(indexEntry{ID: "second/post", Slug: "second"}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if err := second.Put(indexEntry{ID: "third", Slug: "third"}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if err := second.Delete("third"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+
+	for _, idx := range []*postIndex{first, second} {
+		if err := idx.Reload(); err != nil {
+			t.Fatalf("Failed to reload index: %v", err)
+		}
+		if len(idx.posts) != 2 {
+			t.Errorf("Expected entries of both replicas, got %v", idx.posts)
+		}
+		if _, ok := idx.Get("second/post"); !ok {
+			t.Errorf("Expected entry with slash in ID to be kept, got %v", idx.posts)
+		}
+	}
+}
+
+func TestStorageHandler_Private(t *testing.T) {
+	store := newTestStorage(t)
+	index, err := loadIndex(store, indexPrefix)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if err := index.Put(indexEntry{ID: "first", Slug: "first"}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if err := store.Put("first.html", []byte("<html></html>")); err != nil {
+		t.Fatalf("Failed to write page: %v", err)
+	}
+
+	testCases := []struct {
+		path string
+		code int
+	}{
+		{path: "/first.html", code: http.StatusOK},
+		{path: "/posts/first.json", code: http.StatusNotFound},
+		{path: "/tags/../posts/first.json", code: http.StatusNotFound},
+		{path: "/posts/", code: http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			storageHandler(store, indexPrefix).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tC.path, nil))
+			if rec.Code != tC.code {
+				t.Errorf("Expected status %d, got %d", tC.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPostIndex_ResolveSlug(t *testing.T) {
+	idx := &postIndex{posts: map[string]indexEntry{
+		"first": {ID: "first", Slug: "hello"},
+	}}
+
+	testCases := []struct {
+		desc     string
+		id       string
+		slug     string
+		expected string
+	}{
+		{desc: "free", id: "second", slug: "other", expected: "other"},
+		{desc: "own slug", id: "first", slug: "hello", expected: "hello"},
+		{desc: "taken", id: "second", slug: "hello", expected: "hello-352f7829"},
+		{desc: "index page", id: "second", slug: "index", expected: "index-352f7829"},
+		{desc: "feed", id: "second", slug: "feed", expected: "feed-352f7829"},
+		{desc: "tags directory", id: "second", slug: "tags", expected: "tags-352f7829"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if slug := idx.ResolveSlug(tC.id, tC.slug); slug != tC.expected {
+				t.Errorf("Expected %q, got %q", tC.expected, slug)
+			}
+		})
+	}
+}
+
+func TestPostIndex_ByTag(t *testing.T) {
+	idx := &postIndex{posts: map[string]indexEntry{
+		"first":  {ID: "first", Tags: []string{"Go", "go!", "???"}},
+		"second": {ID: "second", Tags: []string{"go", "NATS"}},
+	}}
+
+	tags := idx.ByTag()
+	if len(tags) != 2 {
+		t.Fatalf("Expected go and nats tags, got %v", tags)
+	}
+	if tag := tags["go"]; tag == nil || len(tag.Posts) != 2 {
+		t.Errorf("Expected both posts tagged with go, got %+v", tag)
+	}
+	if tag := tags["nats"]; tag == nil || tag.Name != "NATS" || len(tag.Posts) != 1 {
+		t.Errorf("Expected a single post tagged with NATS, got %+v", tag)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/mux"
@@ -9,6 +11,7 @@ import (
 	"github.com/nats-io/go-nats"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mycodesmells/golang-examples/nats/messaging"
 	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
 )
 
@@ -16,12 +19,18 @@ type config struct {
 	Addr       string `envconfig:"ADDR" default:":8000"`
 	NatsAddr   string `envconfig:"NATS_ADDR" default:"nats://localhost:4222"`
 	StaticPath string `envconfig:"STATIC_PATH" default:"./posts"`
+	BaseURL    string `envconfig:"BASE_URL" default:"http://localhost:8080"`
+	// Replicas sharing a queue group split messages between themselves, so
+	// they should also share STATIC_PATH. Leave empty for every replica to
+	// receive all messages.
+	QueueGroup string `envconfig:"QUEUE_GROUP" default:"blog-generator"`
+	// Themes are read from TEMPLATE_DIR if set, otherwise the embedded ones
+	// are used. TEMPLATE_RELOAD re-reads them before rendering each page.
+	TemplateDir    string `envconfig:"TEMPLATE_DIR"`
+	Theme          string `envconfig:"THEME" default:"default"`
+	TemplateReload bool   `envconfig:"TEMPLATE_RELOAD" default:"false"`
 }
 
-const (
-	topicPublishPost = "posts:publish"
-)
-
 func main() {
 	// Process ENV variables
 	var cfg config
@@ -29,6 +38,16 @@ func main() {
 		log.Fatalf("Failed to load configuration from env: %v", err)
 	}
 
+	// Load templates first, so that broken themes are reported right away
+	tplFS, err := templatesFS(cfg.TemplateDir)
+	if err != nil {
+		log.Fatalf("Failed to open templates: %v", err)
+	}
+	templates, err := loadTemplates(tplFS, cfg.Theme, cfg.TemplateReload)
+	if err != nil {
+		log.Fatalf("Failed to load theme '%s': %v", cfg.Theme, err)
+	}
+
 	// Connect to NATS
 	natsClient, err := nats.Connect(cfg.NatsAddr)
 	if err != nil {
@@ -36,17 +55,25 @@ func main() {
 	}
 
 	// Initialize page generator.
-	gen, err := newPageGenerator(cfg.StaticPath)
+	store, err := newLocalStorage(cfg.StaticPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	gen, err := newPageGenerator(store, templates, cfg.BaseURL)
 	if err != nil {
 		log.Fatalf("Failed to start page generator: %v", err)
 	}
 
 	// Start NATS subscriptions
-	startSubscription(natsClient, topicPublishPost, generatePostPage(gen))
+	posts := messaging.Subscriber{
+		Subject: messaging.PublishPost,
+		New:     func() proto.Message { return &pb.PublishPostMessage{} },
+	}
+	startSubscription(natsClient, posts.Subject.Name, cfg.QueueGroup, generatePostPage(natsClient, posts, gen, cfg.BaseURL))
 
-	// Start HTTP server serving static files
+	// Start HTTP server serving generated files
 	r := mux.NewRouter()
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir(cfg.StaticPath)))
+	r.PathPrefix("/").Handler(storageHandler(store, indexPrefix))
 
 	log.Infof("Starting HTTP server on '%s'", cfg.Addr)
 	if err := http.ListenAndServe(cfg.Addr, r); err != nil {
@@ -54,27 +81,65 @@ func main() {
 	}
 }
 
-// Start subscription and exit if failed.
-func startSubscription(natsClient *nats.Conn, topic string, handler nats.MsgHandler) {
-	if _, err := natsClient.Subscribe(topic, handler); err != nil {
+// Start subscription and exit if failed. With non-empty queue group each
+// message is delivered to only one of its members.
+func startSubscription(natsClient *nats.Conn, topic, queue string, handler nats.MsgHandler) {
+	var err error
+	if queue == "" {
+		_, err = natsClient.Subscribe(topic, handler)
+	} else {
+		_, err = natsClient.QueueSubscribe(topic, queue, handler)
+	}
+	if err != nil {
 		log.Fatalf("Failed to start subscription on '%s': %v", topic, err)
 	}
-	log.Infof("Started subscription on '%s'", topic)
+	log.Infof("Started subscription on '%s' (queue group: '%s')", topic, queue)
 }
 
-// Wrapper for page generation.
-func generatePostPage(gen pageGenerator) nats.MsgHandler {
+// Wrapper for page generation. The outcome is published on results topic
+// and sent as a reply if the publisher waits for one.
+func generatePostPage(natsClient *nats.Conn, posts messaging.Subscriber, gen pageGenerator, baseURL string) nats.MsgHandler {
+	results := messaging.NewPublisher(natsClient, messaging.PostPublished)
+
 	return func(natsMsg *nats.Msg) {
 		log.Debug("Received new post generation queue message")
 
-		var message pb.PublishPostMessage
-		if err := proto.Unmarshal(natsMsg.Data, &message); err != nil {
-			log.Errorf("Failed to unmarshal queue message: %v", err)
+		var result pb.PublishPostResult
+		msg, err := posts.Decode(natsMsg.Data)
+		if err != nil {
+			result.Error = "invalid message"
+			reply(natsClient, natsMsg, &result)
 			return
 		}
+		message := msg.(*pb.PublishPostMessage)
 
-		if err := gen.Generate(message); err != nil {
+		entry, err := gen.Generate(*message)
+		result.Id = entry.ID
+		result.Slug = entry.Slug
+		result.Operation = message.Operation
+		if err != nil {
 			log.Errorf("Failed to generate post page: %v", err)
+			result.Error = err.Error()
+		} else if message.Operation != pb.PublishPostMessage_DELETE {
+			result.Url = fmt.Sprintf("%s/%s.html", strings.TrimSuffix(baseURL, "/"), entry.Slug)
 		}
+
+		if result.Id != "" {
+			publish(results, &result)
+		}
+		reply(natsClient, natsMsg, &result)
+	}
+}
+
+func reply(natsClient *nats.Conn, natsMsg *nats.Msg, result *pb.PublishPostResult) {
+	if natsMsg.Reply == "" {
+		return
+	}
+	publish(messaging.NewPublisher(natsClient, messaging.PostPublished.WithName(natsMsg.Reply)), result)
+}
+
+func publish(publisher messaging.Publisher, msg proto.Message) {
+	if err := publisher.Publish(msg); err != nil {
+		log.Errorf("Failed to publish message: %v", err)
 	}
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday"
+)
+
+// Policy for user generated content, which keeps language hints of code
+// blocks, so that they can be highlighted on the client side.
+var contentPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return p
+}()
+
+// Renders post content written in Markdown into sanitized HTML.
+func renderMarkdown(content string) template.HTML {
+	unsafe := blackfriday.MarkdownCommon([]byte(content))
+	return template.HTML(contentPolicy.SanitizeBytes(unsafe))
+}
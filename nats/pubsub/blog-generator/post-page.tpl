@@ -1,16 +0,0 @@
-<html>
-
-<head>
-    <meta charset="utf-8">
-    <title>{{ .Title }}</title>
-</head>
-
-<body>
-    <h2>{{ .Title }}</h2>
-    <small>Published on {{ .Date }}</small>
-    <p>
-        {{ .Content }}
-    </p>
-</body>
-
-</html>
\ No newline at end of file
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// storage is where generated pages end up. It is modeled after object
+// stores, so that generator replicas can share their output: objects are
+// addressed by slash-separated names and always written as a whole.
+type storage interface {
+	// Get returns contents of the object, or an error satisfying
+	// os.IsNotExist if there is no such object.
+	Get(name string) ([]byte, error)
+	// Put atomically replaces contents of the object.
+	Put(name string, data []byte) error
+	// Delete removes the object. Missing objects are not an error.
+	Delete(name string) error
+	// List returns names of all objects starting with prefix.
+	List(prefix string) ([]string, error)
+}
+
+// Storage keeping objects as files in a local (or mounted) directory.
+type localStorage struct {
+	root string
+}
+
+func newLocalStorage(root string) (localStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return localStorage{}, errors.Wrapf(err, "failed to create '%s' directory", root)
+	}
+	return localStorage{root: root}, nil
+}
+
+func (s localStorage) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (s localStorage) Get(name string) ([]byte, error) {
+	return ioutil.ReadFile(s.path(name))
+}
+
+// Put writes data into a temporary file next to the target one and renames
+// it afterwards, so that readers never see a partially written file.
+func (s localStorage) Put(name string, data []byte) error {
+	fileName := s.path(name)
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create '%s' directory", dir)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(fileName)+".")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer func() {
+		// no-op once the file has been renamed
+		if err := os.Remove(tmp.Name()); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to remove temporary file: %v", err)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync temporary file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary file")
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrap(err, "failed to change file mode")
+	}
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return errors.Wrapf(err, "failed to replace '%s'", fileName)
+	}
+	return nil
+}
+
+func (s localStorage) Delete(name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove '%s'", name)
+	}
+	return nil
+}
+
+func (s localStorage) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list files")
+	}
+	return names, nil
+}
+
+// Serves objects from the storage over HTTP, with index.html being served
+// for directories. Objects starting with any of private prefixes are kept
+// hidden.
+func storageHandler(store storage, private ...string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+		if name == "" || strings.HasSuffix(req.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+		if strings.HasPrefix(path.Base(name), ".") {
+			http.NotFound(rw, req)
+			return
+		}
+		for _, prefix := range private {
+			if strings.HasPrefix(name, prefix) {
+				http.NotFound(rw, req)
+				return
+			}
+		}
+
+		bs, err := store.Get(name)
+		if os.IsNotExist(err) {
+			http.NotFound(rw, req)
+			return
+		}
+		if err != nil {
+			log.Errorf("Failed to read '%s': %v", name, err)
+			http.Error(rw, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+
+		if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+			rw.Header().Set("Content-Type", contentType)
+		}
+		rw.Write(bs)
+	})
+}
@@ -0,0 +1,10 @@
+{{ define "title" }}Blog{{ end }}
+
+{{ define "head" }}
+    <link rel="alternate" type="application/atom+xml" href="feed.xml">
+{{- end }}
+
+{{ define "content" }}
+    <h1>Blog</h1>
+    {{- template "post-list" . }}
+{{- end }}
@@ -0,0 +1,13 @@
+<html>
+
+<head>
+    <meta charset="utf-8">
+    <title>{{ template "title" . }}</title>
+    {{- block "head" . }}{{ end }}
+</head>
+
+<body>
+    {{- template "content" . }}
+</body>
+
+</html>
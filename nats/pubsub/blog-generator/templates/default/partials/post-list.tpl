@@ -0,0 +1,11 @@
+{{ define "post-list" }}
+    <ul>
+        {{- range .Posts }}
+        <li>
+            <a href="{{ $.Root }}{{ .Slug }}.html">{{ .Title }}</a>
+            <small>{{ .Date }}</small>
+            {{ if .Summary }}<p>{{ .Summary }}</p>{{ end }}
+        </li>
+        {{- end }}
+    </ul>
+{{- end }}
@@ -0,0 +1,12 @@
+{{ define "title" }}{{ .Title }}{{ end }}
+
+{{ define "content" }}
+    <h2>{{ .Title }}</h2>
+    <small>
+        Published on {{ .Date }}{{ if .Author }} by {{ .Author }}{{ end }}
+        {{ range $tag := .Tags }}{{ with slugify $tag }}<a href="tags/{{ . }}.html">#{{ $tag }}</a> {{ end }}{{ end }}
+    </small>
+    <article>
+        {{ .Content }}
+    </article>
+{{- end }}
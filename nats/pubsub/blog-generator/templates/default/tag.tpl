@@ -0,0 +1,7 @@
+{{ define "title" }}Posts tagged #{{ .Tag }}{{ end }}
+
+{{ define "content" }}
+    <h1>Posts tagged #{{ .Tag }}</h1>
+    {{- template "post-list" . }}
+    <a href="{{ .Root }}index.html">All posts</a>
+{{- end }}
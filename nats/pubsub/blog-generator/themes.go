@@ -0,0 +1,180 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Themes shipped with the generator. Each theme is a directory with page
+// templates, an optional layout.tpl wrapping them and optional partials.
+//
+//go:embed templates
+var embeddedTemplates embed.FS
+
+const (
+	layoutTemplate   = "layout.tpl"
+	partialsTemplate = "partials/*.tpl"
+)
+
+// Pages every theme needs to provide, with sample data used to check that
+// they can actually be rendered.
+var requiredPages = map[string]interface{}{
+	"post-page.tpl": postPageData{
+		Title:   "Sample post",
+		Content: "<p>Sample content</p>",
+		Date:    sampleEntry.Date(),
+		Author:  sampleEntry.Author,
+		Tags:    sampleEntry.Tags,
+	},
+	"index.tpl": indexPageData{Posts: []indexEntry{sampleEntry}},
+	"tag.tpl":   tagPageData{Tag: "sample", Posts: []indexEntry{sampleEntry}, Root: "../"},
+}
+
+var sampleEntry = indexEntry{
+	ID:      "sample",
+	Title:   "Sample post",
+	Slug:    "sample-post",
+	Author:  "Author",
+	Summary: "Sample summary",
+	Tags:    []string{"sample"},
+}
+
+// Returns embedded themes, or the ones found in dir if it is not empty.
+func templatesFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embeddedTemplates, "templates")
+}
+
+// Templates of a single theme, parsed once and executed by page name. In
+// reload mode they are parsed again before each use, so that changes can
+// be previewed without restarting the generator.
+type themeTemplates struct {
+	fsys   fs.FS
+	theme  string
+	reload bool
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+func loadTemplates(fsys fs.FS, theme string, reload bool) (*themeTemplates, error) {
+	pages, err := parseTheme(fsys, theme)
+	if err != nil {
+		return nil, err
+	}
+	return &themeTemplates{
+		fsys:   fsys,
+		theme:  theme,
+		reload: reload,
+		pages:  pages,
+	}, nil
+}
+
+// Execute renders page with given data.
+func (t *themeTemplates) Execute(w io.Writer, page string, data interface{}) error {
+	if t.reload {
+		pages, err := parseTheme(t.fsys, t.theme)
+		if err != nil {
+			// keep the last working version until the theme gets fixed
+			log.Errorf("Failed to reload theme '%s': %v", t.theme, err)
+		} else {
+			t.mu.Lock()
+			t.pages = pages
+			t.mu.Unlock()
+		}
+	}
+
+	t.mu.RLock()
+	tpl, ok := t.pages[page]
+	t.mu.RUnlock()
+	if !ok {
+		return errors.Errorf("theme '%s' has no '%s' page", t.theme, page)
+	}
+
+	name := page
+	if tpl.Lookup(layoutTemplate) != nil {
+		name = layoutTemplate
+	}
+	return tpl.ExecuteTemplate(w, name, data)
+}
+
+// Parses all pages of the theme and renders them with sample data, so that
+// broken themes are rejected up front instead of on the first post.
+func parseTheme(fsys fs.FS, theme string) (map[string]*template.Template, error) {
+	themeFS, err := fs.Sub(fsys, theme)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid theme name '%s'", theme)
+	}
+	if _, err := fs.Stat(themeFS, "."); err != nil {
+		return nil, errors.Errorf("theme '%s' not found, available themes: %v", theme, listThemes(fsys))
+	}
+
+	base := template.New("").Funcs(templateFuncs)
+	shared, err := fs.Glob(themeFS, partialsTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list partials")
+	}
+	if _, err := fs.Stat(themeFS, layoutTemplate); err == nil {
+		shared = append(shared, layoutTemplate)
+	}
+	if len(shared) > 0 {
+		if base, err = base.ParseFS(themeFS, shared...); err != nil {
+			return nil, errors.Wrap(err, "failed to parse layout")
+		}
+	}
+
+	files, err := fs.Glob(themeFS, "*.tpl")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pages")
+	}
+	pages := make(map[string]*template.Template)
+	for _, file := range files {
+		if file == layoutTemplate {
+			continue
+		}
+		tpl, err := base.Clone()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to clone layout")
+		}
+		if tpl, err = tpl.ParseFS(themeFS, file); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse page '%s'", file)
+		}
+		pages[path.Base(file)] = tpl
+	}
+
+	t := &themeTemplates{theme: theme, pages: pages}
+	for page, data := range requiredPages {
+		if err := t.Execute(ioutil.Discard, page, data); err != nil {
+			return nil, errors.Wrapf(err, "failed to render page '%s'", page)
+		}
+	}
+	return pages, nil
+}
+
+// Names of all themes available in fsys.
+func listThemes(fsys fs.FS) []string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil
+	}
+	var themes []string
+	for _, e := range entries {
+		if e.IsDir() {
+			themes = append(themes, e.Name())
+		}
+	}
+	sort.Strings(themes)
+	return themes
+}
@@ -0,0 +1,7 @@
+// Package integration tests blog-admin and blog-generator together, with
+// both services talking to a NATS server embedded in the test process.
+//
+// Tests build the services first, so they are guarded by integration tag:
+//
+//	go test -tags integration .
+package integration
@@ -0,0 +1,103 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/nats-io/gnatsd/server"
+
+	"github.com/mycodesmells/golang-examples/nats/servicetest"
+)
+
+const apiKey = "integration-test-key"
+
+// Services built once for all tests.
+var bins *servicetest.Binaries
+
+func TestMain(m *testing.M) {
+	b, err := servicetest.Build("blog-admin", "blog-generator")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	bins = b
+
+	code := m.Run()
+	bins.Remove()
+	os.Exit(code)
+}
+
+// Starts NATS server on a random port.
+func runNATS(t *testing.T) *server.Server {
+	s := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go s.Start()
+	if !s.ReadyForConnections(servicetest.StartTimeout) {
+		t.Fatal("NATS server did not start in time")
+	}
+	return s
+}
+
+func natsURL(s *server.Server) string {
+	return fmt.Sprintf("nats://%s", s.Addr())
+}
+
+// Both services connected to the same NATS server, with the generator
+// writing pages into a temporary directory.
+type pipeline struct {
+	nats       *server.Server
+	admin      *servicetest.Service
+	generator  *servicetest.Service
+	adminURL   string
+	blogURL    string
+	staticPath string
+}
+
+func startPipeline(t *testing.T, generatorEnv ...string) *pipeline {
+	staticPath, err := ioutil.TempDir("", "pubsub-static")
+	if err != nil {
+		t.Fatalf("Failed to create static directory: %v", err)
+	}
+
+	p := &pipeline{
+		nats:       runNATS(t),
+		staticPath: staticPath,
+	}
+
+	blogAddr := servicetest.FreeAddr(t)
+	p.blogURL = "http://" + blogAddr
+	p.generator = bins.Start(t, "blog-generator", append([]string{
+		"ADDR=" + blogAddr,
+		"NATS_ADDR=" + natsURL(p.nats),
+		"STATIC_PATH=" + staticPath,
+		"BASE_URL=" + p.blogURL,
+	}, generatorEnv...)...)
+	p.generator.WaitForHTTP(t, blogAddr)
+
+	adminAddr := servicetest.FreeAddr(t)
+	p.adminURL = "http://" + adminAddr
+	p.admin = bins.Start(t, "blog-admin",
+		"ADDR="+adminAddr,
+		"NATS_ADDR="+natsURL(p.nats),
+		"API_KEYS="+apiKey,
+	)
+	p.admin.WaitForHTTP(t, adminAddr)
+
+	return p
+}
+
+func (p *pipeline) Stop(t *testing.T) {
+	p.admin.Stop(t)
+	p.generator.Stop(t)
+	p.nats.Shutdown()
+	os.RemoveAll(p.staticPath)
+}
@@ -0,0 +1,348 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nats "github.com/nats-io/go-nats"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	pb "github.com/mycodesmells/golang-examples/nats/pubsub/proto"
+	"github.com/mycodesmells/golang-examples/nats/servicetest"
+)
+
+type publication struct {
+	ID     string `json:"id"`
+	Slug   string `json:"slug"`
+	Status string `json:"status"`
+	URL    string `json:"url"`
+	Error  string `json:"error"`
+}
+
+type errorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Sends publish request to blog-admin, waiting for the generation result.
+func publish(t *testing.T, p *pipeline, body string) (int, []byte) {
+	req, err := http.NewRequest(http.MethodPost, p.adminURL+"/publish?wait=5s", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("X-API-Key", apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp.StatusCode, bs
+}
+
+func decode(t *testing.T, bs []byte, v interface{}) {
+	if err := json.Unmarshal(bs, v); err != nil {
+		t.Fatalf("Failed to decode response '%s': %v", bs, err)
+	}
+}
+
+func readPage(t *testing.T, p *pipeline, name string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(p.staticPath, name))
+	if err != nil {
+		t.Fatalf("Failed to read '%s': %v", name, err)
+	}
+	return string(bs)
+}
+
+func TestPublishPost(t *testing.T) {
+	p := startPipeline(t)
+	defer p.Stop(t)
+
+	code, bs := publish(t, p, `{
+		"title": "Hello, Integration!",
+		"content": "# Heading\n\nSome *text*<script>alert(1)</script>",
+		"summary": "First post",
+		"tags": ["go"]
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, code, bs)
+	}
+	var pub publication
+	decode(t, bs, &pub)
+	if pub.Status != "published" {
+		t.Fatalf("Expected post to be published, got %+v", pub)
+	}
+	if expected := p.blogURL + "/hello-integration.html"; pub.URL != expected {
+		t.Errorf("Expected URL '%s', got '%s'", expected, pub.URL)
+	}
+
+	testCases := []struct {
+		desc     string
+		page     string
+		contains []string
+		excludes []string
+	}{
+		{
+			desc:     "post page",
+			page:     "hello-integration.html",
+			contains: []string{"<h1>Heading</h1>", "<em>text</em>", `href="tags/go.html"`},
+			excludes: []string{"<script>"},
+		},
+		{
+			desc:     "index page",
+			page:     "index.html",
+			contains: []string{`href="hello-integration.html"`, "First post"},
+		},
+		{
+			desc:     "tag page",
+			page:     "tags/go.html",
+			contains: []string{`href="../hello-integration.html"`},
+		},
+		{
+			desc:     "feed",
+			page:     "feed.xml",
+			contains: []string{pub.URL},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			page := readPage(t, p, tC.page)
+			for _, s := range tC.contains {
+				if !strings.Contains(page, s) {
+					t.Errorf("Expected page to contain '%s':\n%s", s, page)
+				}
+			}
+			for _, s := range tC.excludes {
+				if strings.Contains(page, s) {
+					t.Errorf("Expected page not to contain '%s':\n%s", s, page)
+				}
+			}
+		})
+	}
+
+	resp, err := http.Get(pub.URL)
+	if err != nil {
+		t.Fatalf("Failed to fetch post page: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected post page to be served, got status %d", resp.StatusCode)
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	decode(t, bs, v)
+	return resp.StatusCode
+}
+
+func TestPostStatus(t *testing.T) {
+	p := startPipeline(t)
+	defer p.Stop(t)
+
+	code, bs := publish(t, p, `{"title": "Status Check", "content": "text"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, code, bs)
+	}
+
+	var pub publication
+	if code := getJSON(t, p.adminURL+"/posts/status-check/status", &pub); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if pub.Status != "published" {
+		t.Errorf("Expected post to be published, got %+v", pub)
+	}
+
+	var resp errorResponse
+	if code := getJSON(t, p.adminURL+"/posts/missing/status", &resp); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, code)
+	}
+	if resp.Error == "" {
+		t.Errorf("Expected error message, got %+v", resp)
+	}
+}
+
+func TestMalformedPayload(t *testing.T) {
+	p := startPipeline(t)
+	defer p.Stop(t)
+
+	testCases := []struct {
+		desc   string
+		body   string
+		fields []string
+	}{
+		{
+			desc: "invalid JSON",
+			body: `{"title": `,
+		},
+		{
+			desc:   "missing fields",
+			body:   `{"summary": "no title"}`,
+			fields: []string{"title", "content"},
+		},
+		{
+			desc:   "title without letters",
+			body:   `{"title": " - ", "content": "text"}`,
+			fields: []string{"title"},
+		},
+		{
+			desc:   "title without latin letters",
+			body:   `{"title": "Żółć", "content": "text"}`,
+			fields: []string{"title"},
+		},
+		{
+			desc:   "unknown operation",
+			body:   `{"operation": "archive"}`,
+			fields: []string{"operation"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			code, bs := publish(t, p, tC.body)
+			if code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, code, bs)
+			}
+			var resp errorResponse
+			decode(t, bs, &resp)
+			for _, f := range tC.fields {
+				if _, ok := resp.Fields[f]; !ok {
+					t.Errorf("Expected error for field '%s', got %v", f, resp.Fields)
+				}
+			}
+		})
+	}
+
+	// messages which bypass blog-admin are rejected by the generator
+	nc, err := nats.Connect(natsURL(p.nats))
+	if err != nil {
+		t.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+
+	natsMsg, err := nc.Request(messaging.PublishPost.Name, []byte("not a protobuf message"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to get reply from generator: %v", err)
+	}
+	var result pb.PublishPostResult
+	if err := messaging.PostPublished.Decode(natsMsg.Data, &result); err != nil {
+		t.Fatalf("Failed to decode reply: %v", err)
+	}
+	if result.Error == "" {
+		t.Errorf("Expected malformed message to be rejected, got %+v", result)
+	}
+
+	// and the generator keeps working afterwards
+	code, bs := publish(t, p, `{"title": "Still Alive", "content": "text"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, code, bs)
+	}
+	readPage(t, p, "still-alive.html")
+}
+
+// Theme rendering posts fine, unless their author is "broken".
+var brokenTheme = map[string]string{
+	"post-page.tpl": `{{ .Title }}{{ if eq .Author "broken" }}{{ index .Tags 5 }}{{ end }}`,
+	"index.tpl":     `{{ range .Posts }}{{ .Title }}{{ end }}`,
+	"tag.tpl":       `{{ .Tag }}`,
+}
+
+func writeTheme(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "pubsub-templates")
+	if err != nil {
+		t.Fatalf("Failed to create templates directory: %v", err)
+	}
+	themeDir := filepath.Join(dir, "test")
+	if err := os.MkdirAll(themeDir, 0755); err != nil {
+		t.Fatalf("Failed to create theme directory: %v", err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(themeDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write '%s': %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestTemplateFailure(t *testing.T) {
+	templateDir := writeTheme(t, brokenTheme)
+	defer os.RemoveAll(templateDir)
+
+	p := startPipeline(t, "TEMPLATE_DIR="+templateDir, "THEME=test")
+	defer p.Stop(t)
+
+	code, bs := publish(t, p, `{"title": "Working Post", "content": "text", "author": "fine"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, code, bs)
+	}
+
+	code, bs = publish(t, p, `{"title": "Broken Post", "content": "text", "author": "broken"}`)
+	if code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusInternalServerError, code, bs)
+	}
+	var pub publication
+	decode(t, bs, &pub)
+	if pub.Status != "failed" || pub.Error == "" {
+		t.Errorf("Expected failed publication with error, got %+v", pub)
+	}
+
+	if _, err := os.Stat(filepath.Join(p.staticPath, "broken-post.html")); !os.IsNotExist(err) {
+		t.Errorf("Expected no page for broken post, got: %v", err)
+	}
+	if index := readPage(t, p, "index.html"); index != "Working Post" {
+		t.Errorf("Expected index with working post only, got '%s'", index)
+	}
+}
+
+func TestInvalidThemeFailsFast(t *testing.T) {
+	templateDir := writeTheme(t, map[string]string{
+		"post-page.tpl": `{{ .Title `,
+		"index.tpl":     `{{ range .Posts }}{{ .Title }}{{ end }}`,
+		"tag.tpl":       `{{ .Tag }}`,
+	})
+	defer os.RemoveAll(templateDir)
+
+	s := runNATS(t)
+	defer s.Shutdown()
+
+	generator := bins.Start(t, "blog-generator",
+		"ADDR="+servicetest.FreeAddr(t),
+		"NATS_ADDR="+natsURL(s),
+		"TEMPLATE_DIR="+templateDir,
+		"THEME=test",
+	)
+	defer generator.Stop(t)
+
+	exited, err := generator.Wait(servicetest.StartTimeout)
+	if !exited {
+		t.Fatal("Expected generator to exit with invalid theme")
+	}
+	if err == nil {
+		t.Error("Expected generator to exit with an error")
+	}
+	if out := generator.Output(); !strings.Contains(out, "Failed to load theme") {
+		t.Errorf("Expected theme error in output:\n%s", out)
+	}
+}
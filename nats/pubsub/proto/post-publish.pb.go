@@ -9,6 +9,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	PublishPostMessage
+	PublishPostResult
 */
 package proto
 
@@ -27,9 +28,41 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto1.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type PublishPostMessage_Operation int32
+
+const (
+	PublishPostMessage_CREATE PublishPostMessage_Operation = 0
+	PublishPostMessage_UPDATE PublishPostMessage_Operation = 1
+	PublishPostMessage_DELETE PublishPostMessage_Operation = 2
+)
+
+var PublishPostMessage_Operation_name = map[int32]string{
+	0: "CREATE",
+	1: "UPDATE",
+	2: "DELETE",
+}
+var PublishPostMessage_Operation_value = map[string]int32{
+	"CREATE": 0,
+	"UPDATE": 1,
+	"DELETE": 2,
+}
+
+func (x PublishPostMessage_Operation) String() string {
+	return proto1.EnumName(PublishPostMessage_Operation_name, int32(x))
+}
+func (PublishPostMessage_Operation) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{0, 0}
+}
+
 type PublishPostMessage struct {
-	Title   string `protobuf:"bytes,1,opt,name=title" json:"title,omitempty"`
-	Content string `protobuf:"bytes,2,opt,name=content" json:"content,omitempty"`
+	Title     string                       `protobuf:"bytes,1,opt,name=title" json:"title,omitempty"`
+	Content   string                       `protobuf:"bytes,2,opt,name=content" json:"content,omitempty"`
+	Slug      string                       `protobuf:"bytes,3,opt,name=slug" json:"slug,omitempty"`
+	Id        string                       `protobuf:"bytes,4,opt,name=id" json:"id,omitempty"`
+	Operation PublishPostMessage_Operation `protobuf:"varint,5,opt,name=operation,enum=mycodesmells.golangexamples.nats.pubsub.proto.PublishPostMessage.Operation" json:"operation,omitempty"`
+	Tags      []string                     `protobuf:"bytes,6,rep,name=tags" json:"tags,omitempty"`
+	Author    string                       `protobuf:"bytes,7,opt,name=author" json:"author,omitempty"`
+	Summary   string                       `protobuf:"bytes,8,opt,name=summary" json:"summary,omitempty"`
 }
 
 func (m *PublishPostMessage) Reset()                    { *m = PublishPostMessage{} }
@@ -51,23 +84,125 @@ func (m *PublishPostMessage) GetContent() string {
 	return ""
 }
 
+func (m *PublishPostMessage) GetSlug() string {
+	if m != nil {
+		return m.Slug
+	}
+	return ""
+}
+
+func (m *PublishPostMessage) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PublishPostMessage) GetOperation() PublishPostMessage_Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return PublishPostMessage_CREATE
+}
+
+func (m *PublishPostMessage) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *PublishPostMessage) GetAuthor() string {
+	if m != nil {
+		return m.Author
+	}
+	return ""
+}
+
+func (m *PublishPostMessage) GetSummary() string {
+	if m != nil {
+		return m.Summary
+	}
+	return ""
+}
+
+type PublishPostResult struct {
+	Slug      string                       `protobuf:"bytes,1,opt,name=slug" json:"slug,omitempty"`
+	Url       string                       `protobuf:"bytes,2,opt,name=url" json:"url,omitempty"`
+	Error     string                       `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	Id        string                       `protobuf:"bytes,4,opt,name=id" json:"id,omitempty"`
+	Operation PublishPostMessage_Operation `protobuf:"varint,5,opt,name=operation,enum=mycodesmells.golangexamples.nats.pubsub.proto.PublishPostMessage.Operation" json:"operation,omitempty"`
+}
+
+func (m *PublishPostResult) Reset()                    { *m = PublishPostResult{} }
+func (m *PublishPostResult) String() string            { return proto1.CompactTextString(m) }
+func (*PublishPostResult) ProtoMessage()               {}
+func (*PublishPostResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *PublishPostResult) GetSlug() string {
+	if m != nil {
+		return m.Slug
+	}
+	return ""
+}
+
+func (m *PublishPostResult) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *PublishPostResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *PublishPostResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PublishPostResult) GetOperation() PublishPostMessage_Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return PublishPostMessage_CREATE
+}
+
 func init() {
 	proto1.RegisterType((*PublishPostMessage)(nil), "mycodesmells.golangexamples.nats.pubsub.proto.PublishPostMessage")
+	proto1.RegisterType((*PublishPostResult)(nil), "mycodesmells.golangexamples.nats.pubsub.proto.PublishPostResult")
+	proto1.RegisterEnum("mycodesmells.golangexamples.nats.pubsub.proto.PublishPostMessage.Operation", PublishPostMessage_Operation_name, PublishPostMessage_Operation_value)
 }
 
 func init() { proto1.RegisterFile("proto/post-publish.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 175 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0xce, 0x31, 0x8b, 0x02, 0x31,
-	0x10, 0x05, 0x60, 0xf6, 0xe0, 0xee, 0x30, 0x65, 0xb0, 0x48, 0x29, 0x56, 0x36, 0x9b, 0x14, 0x56,
-	0x62, 0x27, 0xb6, 0xc2, 0x62, 0x69, 0x97, 0xac, 0x43, 0x76, 0x21, 0xc9, 0x04, 0x67, 0x02, 0xfa,
-	0xef, 0xc5, 0x84, 0x05, 0xab, 0xe1, 0x3d, 0xe6, 0xc1, 0x27, 0x54, 0x7e, 0x20, 0xa3, 0xc9, 0x48,
-	0xdc, 0xe7, 0xe2, 0xc2, 0x4c, 0x93, 0xae, 0x95, 0xec, 0xe3, 0x6b, 0xc4, 0x3b, 0x50, 0x84, 0x10,
-	0x48, 0x7b, 0x0c, 0x36, 0x79, 0x78, 0xda, 0x98, 0x03, 0x90, 0x4e, 0x96, 0x49, 0xe7, 0xe2, 0xa8,
-	0xb8, 0xf6, 0xbe, 0x3d, 0x0b, 0x39, 0xb4, 0xfd, 0x80, 0xc4, 0x17, 0x20, 0xb2, 0x1e, 0xe4, 0x5a,
-	0xfc, 0xf2, 0xcc, 0x01, 0x54, 0xb7, 0xe9, 0x76, 0xab, 0x6b, 0x0b, 0x52, 0x89, 0xff, 0x11, 0x13,
-	0x43, 0x62, 0xf5, 0x53, 0xfb, 0x25, 0x9e, 0x8e, 0xb7, 0x83, 0x9f, 0x79, 0x2a, 0x4e, 0x8f, 0x18,
-	0xcd, 0xb7, 0xc0, 0x34, 0x41, 0xbf, 0x10, 0xcc, 0x87, 0x60, 0x1a, 0xc1, 0x54, 0x82, 0xfb, 0xab,
-	0x67, 0xff, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x4d, 0xae, 0x12, 0x08, 0xd4, 0x00, 0x00, 0x00,
+	// 332 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x50, 0x31, 0x4f, 0x02, 0x31,
+	0x18, 0xf5, 0x0e, 0x38, 0xa4, 0x03, 0xc1, 0xc6, 0x98, 0x8e, 0x84, 0x89, 0x85, 0x36, 0xd1, 0xc9,
+	0x38, 0xa9, 0xdc, 0xa4, 0x46, 0x72, 0xc1, 0xc5, 0xad, 0x07, 0xcd, 0xd1, 0xa4, 0x77, 0xbd, 0xf4,
+	0x6b, 0x13, 0xf9, 0x8b, 0xfc, 0x2a, 0xd3, 0xf6, 0x10, 0x12, 0x27, 0x27, 0xa7, 0xbe, 0xf7, 0xd2,
+	0xbc, 0xf7, 0xbe, 0x87, 0x48, 0x6b, 0xb4, 0xd5, 0xac, 0xd5, 0x60, 0x17, 0xad, 0x2b, 0x95, 0x84,
+	0x1d, 0x0d, 0x12, 0x5e, 0xd4, 0xfb, 0x8d, 0xde, 0x0a, 0xa8, 0x85, 0x52, 0x40, 0x2b, 0xad, 0x78,
+	0x53, 0x89, 0x2f, 0x5e, 0xb7, 0x4a, 0x00, 0x6d, 0xb8, 0x05, 0xda, 0xba, 0x12, 0x5c, 0x19, 0xbf,
+	0xcf, 0x0e, 0x29, 0xc2, 0xab, 0x68, 0xb0, 0xd2, 0x60, 0xdf, 0x04, 0x00, 0xaf, 0x04, 0xbe, 0x46,
+	0x03, 0x2b, 0xad, 0x12, 0x24, 0x99, 0x26, 0xf3, 0x51, 0x11, 0x09, 0x26, 0x68, 0xb8, 0xd1, 0x8d,
+	0x15, 0x8d, 0x25, 0x69, 0xd0, 0x8f, 0x14, 0x63, 0xd4, 0x07, 0xe5, 0x2a, 0xd2, 0x0b, 0x72, 0xc0,
+	0x78, 0x8c, 0x52, 0xb9, 0x25, 0xfd, 0xa0, 0xa4, 0x72, 0x8b, 0x25, 0x1a, 0xe9, 0x56, 0x18, 0x6e,
+	0xa5, 0x6e, 0xc8, 0x60, 0x9a, 0xcc, 0xc7, 0xb7, 0x2f, 0xf4, 0x4f, 0x6d, 0xe9, 0xef, 0xa6, 0xf4,
+	0xfd, 0x68, 0x59, 0x9c, 0xdc, 0x7d, 0x1d, 0xcb, 0x2b, 0x20, 0xd9, 0xb4, 0xe7, 0xeb, 0x78, 0x8c,
+	0x6f, 0x50, 0xc6, 0x9d, 0xdd, 0x69, 0x43, 0x86, 0xa1, 0x52, 0xc7, 0xfc, 0x51, 0xe0, 0xea, 0x9a,
+	0x9b, 0x3d, 0xb9, 0x8c, 0x47, 0x75, 0x74, 0xc6, 0xd0, 0xe8, 0xc7, 0x1d, 0x23, 0x94, 0x3d, 0x17,
+	0xf9, 0xe3, 0x3a, 0x9f, 0x5c, 0x78, 0xfc, 0xb1, 0x5a, 0x7a, 0x9c, 0x78, 0xbc, 0xcc, 0x5f, 0xf3,
+	0x75, 0x3e, 0x49, 0x67, 0x87, 0x04, 0x5d, 0x9d, 0x55, 0x2c, 0x04, 0x38, 0x75, 0xda, 0x26, 0x39,
+	0xdb, 0x66, 0x82, 0x7a, 0xce, 0xa8, 0x6e, 0x45, 0x0f, 0xfd, 0xe2, 0xc2, 0x18, 0x6d, 0xba, 0x09,
+	0x23, 0xf9, 0xc7, 0x0d, 0x9f, 0x1e, 0x3e, 0xef, 0x2b, 0x69, 0x77, 0xae, 0xa4, 0x1b, 0x5d, 0xb3,
+	0xf3, 0x0c, 0x16, 0x33, 0x16, 0xc7, 0x10, 0xe6, 0x43, 0x58, 0x0c, 0x61, 0x21, 0xa4, 0xcc, 0xc2,
+	0x73, 0xf7, 0x3d, 0x00, 0x16, 0x71, 0xbd, 0xed, 0xa8, 0x02, 0x00, 0x00,
 }
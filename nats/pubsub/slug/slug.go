@@ -0,0 +1,27 @@
+// Package slug makes URL-friendly names of posts and tags, shared by the
+// admin, which reports them, and the generator, which writes pages under
+// them.
+package slug
+
+import "strings"
+
+// Make returns lowercase letters and digits of s, with single dashes in
+// place of anything else, so that it can't point to other directories.
+// It is empty if s has no ASCII letters or digits.
+func Make(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
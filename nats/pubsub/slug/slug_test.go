@@ -0,0 +1,25 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		title    string
+		expected string
+	}{
+		{desc: "words", title: " Hello, World! ", expected: "hello-world"},
+		{desc: "digits", title: "Go 1.10 released", expected: "go-1-10-released"},
+		{desc: "path", title: "../tags/go", expected: "tags-go"},
+		{desc: "dashes", title: "--a--b--", expected: "a-b"},
+		{desc: "non-ascii", title: "Zażółć gęślą jaźń", expected: "za-g-l-ja"},
+		{desc: "nothing left", title: "???", expected: ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if slug := Make(tC.title); slug != tC.expected {
+				t.Errorf("Expected %q, got %q", tC.expected, slug)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package servicetest runs services of the examples as child processes,
+// for integration tests driving them over HTTP and NATS.
+package servicetest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const (
+	// StartTimeout limits waiting for a service to start or exit.
+	StartTimeout = 10 * time.Second
+	// WaitTimeout limits waiting for a running service to do something.
+	WaitTimeout = 5 * time.Second
+)
+
+// Binaries of services, built once for all tests.
+type Binaries struct {
+	dir string
+}
+
+// Build builds services from sibling directories of the test package,
+// named after the directories.
+func Build(names ...string) (*Binaries, error) {
+	dir, err := ioutil.TempDir("", "integration-bin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create binaries directory: %v", err)
+	}
+
+	for _, name := range names {
+		cmd := exec.Command("go", "build", "-o", filepath.Join(dir, name), "../"+name)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to build %s: %v\n%s", name, err, out)
+		}
+	}
+	return &Binaries{dir: dir}, nil
+}
+
+// Remove deletes the binaries.
+func (b *Binaries) Remove() {
+	os.RemoveAll(b.dir)
+}
+
+// Start runs the service in background, with env added to the environment
+// of the test.
+func (b *Binaries) Start(t *testing.T, name string, env ...string) *Service {
+	out := &syncBuffer{}
+	cmd := exec.Command(filepath.Join(b.dir, name))
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start %s: %v", name, err)
+	}
+
+	s := &Service{
+		Name: name,
+		cmd:  cmd,
+		out:  out,
+		done: make(chan error, 1),
+	}
+	go func() { s.done <- cmd.Wait() }()
+	return s
+}
+
+// Run runs the service with args until it exits, eg. a command line tool,
+// and returns its output.
+func (b *Binaries) Run(t *testing.T, name string, args []string, env ...string) string {
+	cmd := exec.Command(filepath.Join(b.dir, name), args...)
+	cmd.Env = append(os.Environ(), env...)
+	timer := time.AfterFunc(StartTimeout, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %s failed: %v\n%s", name, strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// FreeAddr returns an address with a port free to listen on.
+func FreeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// Service running as a child process, with its output kept in case the
+// test fails.
+type Service struct {
+	Name string
+	cmd  *exec.Cmd
+	out  *syncBuffer
+	done chan error
+}
+
+// Output returns everything the service has written so far.
+func (s *Service) Output() string {
+	return s.out.String()
+}
+
+// Stop shuts the service down gracefully, so that it closes its
+// connections, and logs its output if the test has failed.
+func (s *Service) Stop(t *testing.T) {
+	s.cmd.Process.Signal(syscall.SIGTERM)
+	var err error
+	select {
+	case err = <-s.done:
+	case <-time.After(WaitTimeout):
+		s.cmd.Process.Kill()
+		err = <-s.done
+	}
+	// keep it for another Stop, eg. at the end of the test
+	s.done <- err
+	if t.Failed() {
+		t.Logf("Output of %s:\n%s", s.Name, s.Output())
+	}
+}
+
+// WaitForHTTP waits until the service responds on addr.
+func (s *Service) WaitForHTTP(t *testing.T, addr string) {
+	deadline := time.Now().Add(StartTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.done:
+			s.done <- err
+			t.Fatalf("%s exited before accepting connections: %v\n%s", s.Name, err, s.Output())
+		default:
+		}
+
+		resp, err := http.Get("http://" + addr)
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("%s did not start in time", s.Name)
+}
+
+// WaitForOutput waits until the service writes text to its output count
+// times.
+func (s *Service) WaitForOutput(t *testing.T, text string, count int) {
+	deadline := time.Now().Add(WaitTimeout)
+	for time.Now().Before(deadline) {
+		if strings.Count(s.Output(), text) >= count {
+			return
+		}
+		select {
+		case err := <-s.done:
+			s.done <- err
+			t.Fatalf("%s exited before writing '%s': %v\n%s", s.Name, text, err, s.Output())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Fatalf("%s did not write '%s' %d time(s) in time", s.Name, text, count)
+}
+
+// Wait waits for the service to exit on its own.
+func (s *Service) Wait(timeout time.Duration) (exited bool, err error) {
+	select {
+	case err := <-s.done:
+		// let Stop return right away
+		s.done <- err
+		return true, err
+	case <-time.After(timeout):
+		return false, nil
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
+	pb "github.com/mycodesmells/golang-examples/nats/streaming/proto"
+)
+
+// Consumer applying published episodes onto the catalog store.
+type consumer struct {
+	store    store
+	episodes messaging.Subscriber
+	progress progress
+}
+
+func newConsumer(store store) *consumer {
+	return &consumer{
+		store: store,
+		episodes: messaging.Subscriber{
+			Subject: messaging.PublishEpisode,
+			New:     func() proto.Message { return &pb.PublishEpisodeMessage{} },
+		},
+		progress: progress{pending: make(map[uint64]bool)},
+	}
+}
+
+// Start subscribes right after the last message applied onto the store,
+// or at the beginning of the channel if the store is empty. Sequences are
+// kept in the store rather than in a durable subscription, so that the
+// catalog can be rebuilt by resetting the store alone.
+func (c *consumer) Start(conn streaming.Conn, ackWait time.Duration) (streaming.Subscription, error) {
+	lastSeq, err := c.store.LastSequence()
+	if err != nil {
+		return nil, err
+	}
+
+	start := streaming.StartPosition{Mode: streaming.StartAll}
+	if lastSeq > 0 {
+		start = streaming.StartPosition{Mode: streaming.StartAtSequence, Sequence: lastSeq + 1}
+	}
+	sub, err := conn.Subscribe(c.episodes.Subject.Name, c.handle, streaming.SubscriptionOptions{
+		Start:      start,
+		ManualAcks: true,
+		AckWait:    ackWait,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to '%s'", c.episodes.Subject.Name)
+	}
+	log.Infof("Started catalog consumer on '%s' after message #%d", c.episodes.Subject.Name, lastSeq)
+	return sub, nil
+}
+
+// Invalid messages are skipped, so that they don't hold the catalog back,
+// but messages which could not be stored are redelivered. Until then, the
+// recorded sequence stays below them, so they are not lost on restart.
+func (c *consumer) handle(streamMsg *streaming.Msg) {
+	c.progress.Delivered(streamMsg.Sequence)
+
+	msg, err := c.episodes.Decode(streamMsg.Data)
+	if err != nil {
+		c.progress.Applied(streamMsg.Sequence)
+		ack(streamMsg)
+		return
+	}
+
+	ep, err := newEpisode(streamMsg, msg.(*pb.PublishEpisodeMessage))
+	if err != nil {
+		log.Warnf("Skipping message #%d: %v", streamMsg.Sequence, err)
+		c.progress.Applied(streamMsg.Sequence)
+		ack(streamMsg)
+		return
+	}
+
+	added, err := c.store.Add(ep, c.progress.LastSequence(streamMsg.Sequence))
+	if err != nil {
+		log.Errorf("Failed to store message #%d: %v", streamMsg.Sequence, err)
+		return
+	}
+	c.progress.Applied(streamMsg.Sequence)
+	if added {
+		log.Infof("Added S%02dE%02d of '%s' to the catalog", ep.SeasonNo, ep.EpisodeNo, ep.SeriesName)
+	} else {
+		log.Debugf("Skipping duplicate of S%02dE%02d of '%s' in message #%d", ep.SeasonNo, ep.EpisodeNo, ep.SeriesName, streamMsg.Sequence)
+	}
+	ack(streamMsg)
+}
+
+func newEpisode(streamMsg *streaming.Msg, message *pb.PublishEpisodeMessage) (episode, error) {
+	if message.SeriesName == "" {
+		return episode{}, errors.New("missing series name")
+	}
+	if message.SeasonNo < 1 || message.EpisodeNo < 1 {
+		return episode{}, errors.Errorf("invalid episode number S%02dE%02d", message.SeasonNo, message.EpisodeNo)
+	}
+	if _, err := url.ParseRequestURI(message.EpisodeUrl); err != nil {
+		return episode{}, errors.Wrap(err, "invalid episode URL")
+	}
+
+	return episode{
+		SeriesName: message.SeriesName,
+		SeasonNo:   message.SeasonNo,
+		EpisodeNo:  message.EpisodeNo,
+		EpisodeURL: message.EpisodeUrl,
+		Sequence:   streamMsg.Sequence,
+		Published:  time.Unix(0, streamMsg.Timestamp).UTC(),
+	}, nil
+}
+
+// Progress of the consumer, with sequences of messages delivered but not
+// applied yet. Sequences are not contiguous, as JetStream numbers messages
+// of the whole stream, so only delivered ones are tracked.
+type progress struct {
+	mu      sync.Mutex
+	pending map[uint64]bool
+	// the highest sequence applied
+	applied uint64
+}
+
+// Delivered marks message as pending until it is applied.
+func (p *progress) Delivered(seq uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if seq > p.applied {
+		p.pending[seq] = true
+	}
+}
+
+func (p *progress) Applied(seq uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, seq)
+	if seq > p.applied {
+		p.applied = seq
+	}
+}
+
+// LastSequence returns the sequence up to which all delivered messages
+// would be applied, once message seq is.
+func (p *progress) LastSequence(seq uint64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last := p.applied
+	if seq > last {
+		last = seq
+	}
+	for pending := range p.pending {
+		if pending != seq && pending <= last {
+			last = pending - 1
+		}
+	}
+	return last
+}
+
+func ack(streamMsg *streaming.Msg) {
+	if err := streamMsg.Ack(); err != nil {
+		log.Errorf("Failed to acknowledge message #%d: %v", streamMsg.Sequence, err)
+	}
+}
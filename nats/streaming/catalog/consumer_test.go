@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestProgress(t *testing.T) {
+	type step struct {
+		// call is either "applied" or "failed", which leaves the message
+		// pending
+		call string
+		seq  uint64
+		// last is the expected sequence recorded along with the message
+		last uint64
+	}
+
+	testCases := []struct {
+		desc  string
+		steps []step
+	}{
+		{
+			desc: "in order",
+			steps: []step{
+				{call: "applied", seq: 1, last: 1},
+				{call: "applied", seq: 2, last: 2},
+				{call: "applied", seq: 3, last: 3},
+			},
+		},
+		{
+			desc: "gaps of other subjects",
+			steps: []step{
+				{call: "applied", seq: 2, last: 2},
+				{call: "applied", seq: 5, last: 5},
+			},
+		},
+		{
+			desc: "held back by failed message",
+			steps: []step{
+				{call: "applied", seq: 1, last: 1},
+				{call: "failed", seq: 2},
+				{call: "applied", seq: 3, last: 1},
+				{call: "applied", seq: 4, last: 1},
+				{call: "applied", seq: 2, last: 4},
+				{call: "applied", seq: 5, last: 5},
+			},
+		},
+		{
+			desc: "several failed messages",
+			steps: []step{
+				{call: "failed", seq: 3},
+				{call: "failed", seq: 4},
+				{call: "applied", seq: 5, last: 2},
+				{call: "applied", seq: 4, last: 2},
+				{call: "applied", seq: 3, last: 5},
+			},
+		},
+		{
+			desc: "redelivered after being applied",
+			steps: []step{
+				{call: "applied", seq: 1, last: 1},
+				{call: "applied", seq: 2, last: 2},
+				{call: "applied", seq: 1, last: 2},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := progress{pending: make(map[uint64]bool)}
+			for i, s := range tC.steps {
+				p.Delivered(s.seq)
+				if s.call == "failed" {
+					continue
+				}
+				if last := p.LastSequence(s.seq); last != s.last {
+					t.Fatalf("Step %d: expected last sequence %d, got %d", i, s.last, last)
+				}
+				p.Applied(s.seq)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
+)
+
+var (
+	clusterID = "test-cluster"
+)
+
+type config struct {
+	Addr     string `envconfig:"ADDR" default:":8000"`
+	NatsAddr string `envconfig:"NATS_ADDR" default:"nats://localhost:4222"`
+
+	// Backend is either "stan" (NATS Streaming) or "jetstream".
+	Backend string        `envconfig:"BACKEND" default:"stan"`
+	Stream  string        `envconfig:"STREAM" default:"EPISODES"`
+	AckWait time.Duration `envconfig:"ACK_WAIT" default:"30s"`
+
+	// Store is either "memory" or "sqlite". In-memory catalog is rebuilt
+	// from the channel on every start, SQLite one only when Rebuild is set.
+	Store      string `envconfig:"STORE" default:"memory"`
+	SQLitePath string `envconfig:"SQLITE_PATH" default:"catalog.db"`
+	Rebuild    bool   `envconfig:"REBUILD" default:"false"`
+}
+
+func main() {
+	// Process ENV variables
+	var cfg config
+	if err := envconfig.Process("catalog", &cfg); err != nil {
+		log.Fatalf("Failed to load configuration from env: %v", err)
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if cfg.Rebuild {
+		if err := store.Reset(); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Rebuilding catalog from the beginning of the channel")
+	}
+
+	// Connect to NATS-Streaming or JetStream
+	natsClient, err := streaming.Connect(streaming.Config{
+		Backend:   cfg.Backend,
+		URL:       cfg.NatsAddr,
+		ClusterID: clusterID,
+		ClientID:  uuid.NewV4().String(),
+	})
+	if err != nil {
+		log.Fatalf("Can't connect: %v.\nMake sure a NATS Streaming Server (or NATS with JetStream) is running at: %s", err, cfg.NatsAddr)
+	}
+	defer natsClient.Close()
+
+	subject := messaging.PublishEpisode
+	if err := natsClient.EnsureStream(cfg.Stream, subject.Name, subject.DeadLetter().Name); err != nil {
+		log.Fatal(err)
+	}
+
+	sub, err := newConsumer(store).Start(natsClient, cfg.AckWait)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sub.Close()
+
+	// Serve HTTP
+	srv := server{store: store}
+	r := mux.NewRouter()
+	r.HandleFunc("/series", srv.HandleSeries).Methods(http.MethodGet)
+	r.HandleFunc("/series/{name}/seasons/{season}", srv.HandleSeason).Methods(http.MethodGet)
+	r.HandleFunc("/latest", srv.HandleLatest).Methods(http.MethodGet)
+
+	log.Infof("Starting HTTP server on '%s'", cfg.Addr)
+	if err := http.ListenAndServe(cfg.Addr, r); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func openStore(cfg config) (store, error) {
+	switch cfg.Store {
+	case "memory":
+		return newMemoryStore(), nil
+	case "sqlite":
+		return openSQLiteStore(cfg.SQLitePath)
+	default:
+		return nil, errors.Errorf("unknown store '%s', expected 'memory' or 'sqlite'", cfg.Store)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// Store keeping the catalog in memory only, so it is rebuilt from the
+// stream on every start.
+type memoryStore struct {
+	mu       sync.RWMutex
+	episodes map[episodeKey]episode
+	lastSeq  uint64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		episodes: make(map[episodeKey]episode),
+	}
+}
+
+func (s *memoryStore) Add(ep episode, lastSeq uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lastSeq > s.lastSeq {
+		s.lastSeq = lastSeq
+	}
+	if _, exists := s.episodes[ep.key()]; exists {
+		return false, nil
+	}
+	s.episodes[ep.key()] = ep
+	return true, nil
+}
+
+func (s *memoryStore) LastSequence() (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSeq, nil
+}
+
+func (s *memoryStore) Series() ([]series, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byName := make(map[string]*series)
+	seasons := make(map[string]map[int64]bool)
+	for _, ep := range s.episodes {
+		sr, ok := byName[ep.SeriesName]
+		if !ok {
+			sr = &series{Name: ep.SeriesName}
+			byName[ep.SeriesName] = sr
+			seasons[ep.SeriesName] = make(map[int64]bool)
+		}
+		sr.Episodes++
+		if ep.Published.After(sr.Updated) {
+			sr.Updated = ep.Published
+		}
+		if !seasons[ep.SeriesName][ep.SeasonNo] {
+			seasons[ep.SeriesName][ep.SeasonNo] = true
+			sr.Seasons = append(sr.Seasons, ep.SeasonNo)
+		}
+	}
+
+	all := make([]series, 0, len(byName))
+	for _, sr := range byName {
+		sort.Slice(sr.Seasons, func(a, b int) bool { return sr.Seasons[a] < sr.Seasons[b] })
+		all = append(all, *sr)
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a].Name < all[b].Name })
+	return all, nil
+}
+
+func (s *memoryStore) Season(seriesName string, seasonNo int64) ([]episode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var eps []episode
+	for _, ep := range s.episodes {
+		if ep.SeriesName == seriesName && ep.SeasonNo == seasonNo {
+			eps = append(eps, ep)
+		}
+	}
+	if len(eps) == 0 {
+		return nil, errNotFound
+	}
+	sort.Slice(eps, func(a, b int) bool { return eps[a].EpisodeNo < eps[b].EpisodeNo })
+	return eps, nil
+}
+
+func (s *memoryStore) Latest(limit int) ([]episode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	eps := make([]episode, 0, len(s.episodes))
+	for _, ep := range s.episodes {
+		eps = append(eps, ep)
+	}
+	sort.Slice(eps, func(a, b int) bool { return eps[a].Sequence > eps[b].Sequence })
+	if len(eps) > limit {
+		eps = eps[:limit]
+	}
+	return eps, nil
+}
+
+func (s *memoryStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.episodes = make(map[episodeKey]episode)
+	s.lastSeq = 0
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
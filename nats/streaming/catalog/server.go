@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLatestLimit = 20
+	maxLatestLimit     = 100
+)
+
+type seasonResponse struct {
+	SeriesName string    `json:"series_name"`
+	SeasonNo   int64     `json:"season_no"`
+	Episodes   []episode `json:"episodes"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type server struct {
+	store store
+}
+
+func (s server) HandleSeries(rw http.ResponseWriter, req *http.Request) {
+	all, err := s.store.Series()
+	if err != nil {
+		log.Errorf("Failed to list series: %v", err)
+		writeError(rw, http.StatusInternalServerError, "Failed to list series")
+		return
+	}
+	writeJSON(rw, http.StatusOK, all)
+}
+
+func (s server) HandleSeason(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	seasonNo, err := strconv.ParseInt(vars["season"], 10, 64)
+	if err != nil || seasonNo < 1 {
+		writeError(rw, http.StatusBadRequest, "Season number must be a positive integer")
+		return
+	}
+
+	eps, err := s.store.Season(vars["name"], seasonNo)
+	if err == errNotFound {
+		writeError(rw, http.StatusNotFound, "Season not found")
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to get season %d of '%s': %v", seasonNo, vars["name"], err)
+		writeError(rw, http.StatusInternalServerError, "Failed to get season")
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, seasonResponse{
+		SeriesName: vars["name"],
+		SeasonNo:   seasonNo,
+		Episodes:   eps,
+	})
+}
+
+// HandleLatest returns feed of the most recent episodes, of all series.
+// Its length can be changed with limit query parameter.
+func (s server) HandleLatest(rw http.ResponseWriter, req *http.Request) {
+	limit := defaultLatestLimit
+	if l := req.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxLatestLimit {
+			writeError(rw, http.StatusBadRequest, "Limit must be between 1 and "+strconv.Itoa(maxLatestLimit))
+			return
+		}
+		limit = n
+	}
+
+	eps, err := s.store.Latest(limit)
+	if err != nil {
+		log.Errorf("Failed to get latest episodes: %v", err)
+		writeError(rw, http.StatusInternalServerError, "Failed to get latest episodes")
+		return
+	}
+	writeJSON(rw, http.StatusOK, eps)
+}
+
+func writeError(rw http.ResponseWriter, code int, msg string) {
+	writeJSON(rw, code, errorResponse{Error: msg})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Errorf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS episodes (
+	series_name  TEXT    NOT NULL,
+	season_no    INTEGER NOT NULL,
+	episode_no   INTEGER NOT NULL,
+	episode_url  TEXT    NOT NULL,
+	sequence     INTEGER NOT NULL,
+	published_at INTEGER NOT NULL,
+	PRIMARY KEY (series_name, season_no, episode_no)
+);
+CREATE INDEX IF NOT EXISTS episodes_sequence ON episodes (sequence);
+CREATE TABLE IF NOT EXISTS consumer (
+	id            INTEGER PRIMARY KEY CHECK (id = 1),
+	last_sequence INTEGER NOT NULL
+);
+INSERT OR IGNORE INTO consumer (id, last_sequence) VALUES (1, 0);
+`
+
+// Store keeping the catalog in SQLite database, so that consumer resumes
+// where it stopped.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database '%s'", path)
+	}
+	// SQLite allows a single writer anyway
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create schema")
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Add(ep episode, lastSeq uint64) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO episodes
+		(series_name, season_no, episode_no, episode_url, sequence, published_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		ep.SeriesName, ep.SeasonNo, ep.EpisodeNo, ep.EpisodeURL, ep.Sequence, ep.Published.UnixNano())
+	if err != nil {
+		return false, errors.Wrap(err, "failed to insert episode")
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to insert episode")
+	}
+
+	_, err = tx.Exec(`UPDATE consumer SET last_sequence = ? WHERE id = 1 AND last_sequence < ?`, lastSeq, lastSeq)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to update last sequence")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrap(err, "failed to commit transaction")
+	}
+	return added == 1, nil
+}
+
+func (s *sqliteStore) LastSequence() (uint64, error) {
+	var seq uint64
+	if err := s.db.QueryRow(`SELECT last_sequence FROM consumer WHERE id = 1`).Scan(&seq); err != nil {
+		return 0, errors.Wrap(err, "failed to read last sequence")
+	}
+	return seq, nil
+}
+
+func (s *sqliteStore) Series() ([]series, error) {
+	rows, err := s.db.Query(`SELECT series_name, group_concat(DISTINCT season_no), count(*), max(published_at)
+		FROM episodes GROUP BY series_name ORDER BY series_name`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query series")
+	}
+	defer rows.Close()
+
+	all := []series{}
+	for rows.Next() {
+		var (
+			sr        series
+			seasons   string
+			published int64
+		)
+		if err := rows.Scan(&sr.Name, &seasons, &sr.Episodes, &published); err != nil {
+			return nil, errors.Wrap(err, "failed to read series")
+		}
+		if sr.Seasons, err = parseSeasons(seasons); err != nil {
+			return nil, err
+		}
+		sr.Updated = time.Unix(0, published).UTC()
+		all = append(all, sr)
+	}
+	return all, errors.Wrap(rows.Err(), "failed to read series")
+}
+
+func (s *sqliteStore) Season(seriesName string, seasonNo int64) ([]episode, error) {
+	eps, err := s.queryEpisodes(`SELECT series_name, season_no, episode_no, episode_url, sequence, published_at
+		FROM episodes WHERE series_name = ? AND season_no = ? ORDER BY episode_no`, seriesName, seasonNo)
+	if err != nil {
+		return nil, err
+	}
+	if len(eps) == 0 {
+		return nil, errNotFound
+	}
+	return eps, nil
+}
+
+func (s *sqliteStore) Latest(limit int) ([]episode, error) {
+	return s.queryEpisodes(`SELECT series_name, season_no, episode_no, episode_url, sequence, published_at
+		FROM episodes ORDER BY sequence DESC LIMIT ?`, limit)
+}
+
+func (s *sqliteStore) queryEpisodes(query string, args ...interface{}) ([]episode, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query episodes")
+	}
+	defer rows.Close()
+
+	eps := []episode{}
+	for rows.Next() {
+		var (
+			ep        episode
+			published int64
+		)
+		if err := rows.Scan(&ep.SeriesName, &ep.SeasonNo, &ep.EpisodeNo, &ep.EpisodeURL, &ep.Sequence, &published); err != nil {
+			return nil, errors.Wrap(err, "failed to read episode")
+		}
+		ep.Published = time.Unix(0, published).UTC()
+		eps = append(eps, ep)
+	}
+	return eps, errors.Wrap(rows.Err(), "failed to read episodes")
+}
+
+func (s *sqliteStore) Reset() error {
+	_, err := s.db.Exec(`DELETE FROM episodes; UPDATE consumer SET last_sequence = 0 WHERE id = 1;`)
+	return errors.Wrap(err, "failed to reset catalog")
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// Parses comma-separated season numbers into a sorted slice.
+func parseSeasons(s string) ([]int64, error) {
+	var seasons []int64
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid season number '%s'", part)
+		}
+		seasons = append(seasons, n)
+	}
+	sort.Slice(seasons, func(a, b int) bool { return seasons[a] < seasons[b] })
+	return seasons, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var errNotFound = errors.New("not found")
+
+// Single episode of a series, as published on the stream.
+type episode struct {
+	SeriesName string    `json:"series_name"`
+	SeasonNo   int64     `json:"season_no"`
+	EpisodeNo  int64     `json:"episode_no"`
+	EpisodeURL string    `json:"episode_url"`
+	Sequence   uint64    `json:"sequence"`
+	Published  time.Time `json:"published"`
+}
+
+// Summary of a series with all of its seasons.
+type series struct {
+	Name     string    `json:"name"`
+	Seasons  []int64   `json:"seasons"`
+	Episodes int       `json:"episodes"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Catalog of series materialized from the stream. Along with episodes,
+// stores keep sequence of the last message applied, so that consumer can
+// continue right after it.
+type store interface {
+	// Add stores the episode, unless one with the same series, season and
+	// episode number already exists. It reports whether the episode has
+	// been added and records lastSeq either way, as the sequence up to
+	// which all messages have been applied.
+	Add(ep episode, lastSeq uint64) (bool, error)
+	LastSequence() (uint64, error)
+
+	// All series, sorted by name.
+	Series() ([]series, error)
+	// Episodes of a season, sorted by number. Returns errNotFound if the
+	// series has no such season.
+	Season(seriesName string, seasonNo int64) ([]episode, error)
+	// The most recently published episodes, the newest first.
+	Latest(limit int) ([]episode, error)
+
+	// Reset removes all episodes, so that the catalog can be rebuilt.
+	Reset() error
+	Close() error
+}
+
+type episodeKey struct {
+	seriesName string
+	seasonNo   int64
+	episodeNo  int64
+}
+
+func (e episode) key() episodeKey {
+	return episodeKey{e.SeriesName, e.SeasonNo, e.EpisodeNo}
+}
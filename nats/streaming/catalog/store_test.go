@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testEpisodes = []episode{
+	{SeriesName: "Dark", SeasonNo: 1, EpisodeNo: 2, EpisodeURL: "https://example.com/dark/s01e02", Sequence: 1},
+	{SeriesName: "Dark", SeasonNo: 1, EpisodeNo: 1, EpisodeURL: "https://example.com/dark/s01e01", Sequence: 2},
+	{SeriesName: "Dark", SeasonNo: 2, EpisodeNo: 1, EpisodeURL: "https://example.com/dark/s02e01", Sequence: 3},
+	{SeriesName: "Babylon Berlin", SeasonNo: 1, EpisodeNo: 1, EpisodeURL: "https://example.com/bb/s01e01", Sequence: 4},
+	// replayed with another sequence and URL
+	{SeriesName: "Dark", SeasonNo: 1, EpisodeNo: 2, EpisodeURL: "https://example.com/dark/s01e02-replay", Sequence: 5},
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, s store)) {
+	testCases := []struct {
+		desc string
+		open func(t *testing.T) (store, func())
+	}{
+		{
+			desc: "memory",
+			open: func(t *testing.T) (store, func()) {
+				return newMemoryStore(), func() {}
+			},
+		},
+		{
+			desc: "sqlite",
+			open: func(t *testing.T) (store, func()) {
+				dir, err := ioutil.TempDir("", "catalog")
+				if err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+				s, err := openSQLiteStore(filepath.Join(dir, "catalog.db"))
+				if err != nil {
+					os.RemoveAll(dir)
+					t.Fatalf("Failed to open store: %v", err)
+				}
+				return s, func() {
+					s.Close()
+					os.RemoveAll(dir)
+				}
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, cleanup := tC.open(t)
+			defer cleanup()
+			test(t, s)
+		})
+	}
+}
+
+func addEpisodes(t *testing.T, s store) []bool {
+	published := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var added []bool
+	for _, ep := range testEpisodes {
+		ep.Published = published.Add(time.Duration(ep.Sequence) * time.Hour)
+		ok, err := s.Add(ep, ep.Sequence)
+		if err != nil {
+			t.Fatalf("Failed to add episode #%d: %v", ep.Sequence, err)
+		}
+		added = append(added, ok)
+	}
+	return added
+}
+
+func TestStoreAddDeduplicates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store) {
+		added := addEpisodes(t, s)
+		if expected := []bool{true, true, true, true, false}; !reflect.DeepEqual(added, expected) {
+			t.Errorf("Expected episodes to be added as %v, got %v", expected, added)
+		}
+
+		seq, err := s.LastSequence()
+		if err != nil {
+			t.Fatalf("Failed to get last sequence: %v", err)
+		}
+		if seq != 5 {
+			t.Errorf("Expected last sequence 5, got %d", seq)
+		}
+
+		eps, err := s.Season("Dark", 1)
+		if err != nil {
+			t.Fatalf("Failed to get season: %v", err)
+		}
+		if url := eps[1].EpisodeURL; url != "https://example.com/dark/s01e02" {
+			t.Errorf("Expected the first publication to be kept, got '%s'", url)
+		}
+	})
+}
+
+func TestStoreQueries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store) {
+		addEpisodes(t, s)
+
+		all, err := s.Series()
+		if err != nil {
+			t.Fatalf("Failed to list series: %v", err)
+		}
+		var names []string
+		for _, sr := range all {
+			names = append(names, sr.Name)
+		}
+		if expected := []string{"Babylon Berlin", "Dark"}; !reflect.DeepEqual(names, expected) {
+			t.Fatalf("Expected series %v, got %v", expected, names)
+		}
+		if dark := all[1]; !reflect.DeepEqual(dark.Seasons, []int64{1, 2}) || dark.Episodes != 3 {
+			t.Errorf("Expected 3 episodes in seasons [1 2], got %d in %v", dark.Episodes, dark.Seasons)
+		}
+
+		eps, err := s.Season("Dark", 1)
+		if err != nil {
+			t.Fatalf("Failed to get season: %v", err)
+		}
+		if len(eps) != 2 || eps[0].EpisodeNo != 1 || eps[1].EpisodeNo != 2 {
+			t.Errorf("Expected episodes 1 and 2 in order, got %+v", eps)
+		}
+
+		if _, err := s.Season("Dark", 3); err != errNotFound {
+			t.Errorf("Expected errNotFound for missing season, got %v", err)
+		}
+
+		latest, err := s.Latest(2)
+		if err != nil {
+			t.Fatalf("Failed to get latest episodes: %v", err)
+		}
+		if len(latest) != 2 || latest[0].Sequence != 4 || latest[1].Sequence != 3 {
+			t.Errorf("Expected episodes #4 and #3, got %+v", latest)
+		}
+	})
+}
+
+func TestStoreReset(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store) {
+		addEpisodes(t, s)
+
+		if err := s.Reset(); err != nil {
+			t.Fatalf("Failed to reset store: %v", err)
+		}
+
+		if seq, err := s.LastSequence(); err != nil || seq != 0 {
+			t.Errorf("Expected last sequence 0, got %d (%v)", seq, err)
+		}
+		if all, err := s.Series(); err != nil || len(all) != 0 {
+			t.Errorf("Expected no series, got %v (%v)", all, err)
+		}
+		if added := addEpisodes(t, s); !added[0] {
+			t.Error("Expected episode to be added again after reset")
+		}
+	})
+}
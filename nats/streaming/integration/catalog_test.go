@@ -0,0 +1,141 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mycodesmells/golang-examples/nats/servicetest"
+)
+
+type catalogEpisode struct {
+	SeriesName string `json:"series_name"`
+	SeasonNo   int64  `json:"season_no"`
+	EpisodeNo  int64  `json:"episode_no"`
+	EpisodeURL string `json:"episode_url"`
+}
+
+type catalogSeries struct {
+	Name     string  `json:"name"`
+	Seasons  []int64 `json:"seasons"`
+	Episodes int     `json:"episodes"`
+}
+
+type catalogSeason struct {
+	Episodes []catalogEpisode `json:"episodes"`
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(bs, v); err != nil {
+			t.Fatalf("Failed to decode response '%s': %v", bs, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// Waits until the catalog has given number of episodes of the test series.
+func waitForEpisodes(t *testing.T, catalogURL string, count int) {
+	deadline := time.Now().Add(servicetest.WaitTimeout)
+	for {
+		var all []catalogSeries
+		getJSON(t, catalogURL+"/series", &all)
+		if len(all) == 1 && all[0].Episodes == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d episodes in catalog, got %+v", count, all)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			// published before the catalog starts, including a replay
+			publishEpisode(t, p, 2)
+			publishEpisode(t, p, 1)
+			publishEpisode(t, p, 2)
+
+			_, catalogURL := p.StartCatalog(t)
+			waitForEpisodes(t, catalogURL, 2)
+
+			publishEpisode(t, p, 3)
+			waitForEpisodes(t, catalogURL, 3)
+
+			var season catalogSeason
+			if code := getJSON(t, catalogURL+"/series/Dark/seasons/1", &season); code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", code)
+			}
+			for i, ep := range season.Episodes {
+				if ep.EpisodeNo != int64(i+1) {
+					t.Errorf("Expected episode %d at position %d, got %d", i+1, i, ep.EpisodeNo)
+				}
+			}
+
+			if code := getJSON(t, catalogURL+"/series/Dark/seasons/2", nil); code != http.StatusNotFound {
+				t.Errorf("Expected status 404 for missing season, got %d", code)
+			}
+
+			var latest []catalogEpisode
+			getJSON(t, catalogURL+"/latest?limit=2", &latest)
+			if len(latest) != 2 || latest[0].EpisodeNo != 3 || latest[1].EpisodeNo != 1 {
+				t.Errorf("Expected episodes 3 and 1 in latest feed, got %+v", latest)
+			}
+		})
+	}
+}
+
+func TestCatalogResumeAndRebuild(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			dir, err := ioutil.TempDir("", "catalog")
+			if err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			env := []string{"STORE=sqlite", "SQLITE_PATH=" + filepath.Join(dir, "catalog.db")}
+
+			c, catalogURL := p.StartCatalog(t, env...)
+			publishEpisode(t, p, 1)
+			publishEpisode(t, p, 2)
+			waitForEpisodes(t, catalogURL, 2)
+			c.Stop(t)
+
+			// continues after the last stored message
+			c, catalogURL = p.StartCatalog(t, env...)
+			c.WaitForOutput(t, "after message #2", 1)
+			waitForEpisodes(t, catalogURL, 2)
+			c.Stop(t)
+
+			// replays the whole channel
+			c, catalogURL = p.StartCatalog(t, append(env, "REBUILD=true")...)
+			c.WaitForOutput(t, "after message #0", 1)
+			waitForEpisodes(t, catalogURL, 2)
+		})
+	}
+}
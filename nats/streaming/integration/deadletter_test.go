@@ -0,0 +1,106 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/nats-io/nats.go"
+	stan "github.com/nats-io/stan.go"
+)
+
+// Encodes episode which passes decoding, but is rejected by the watcher
+// for missing series name. It is built field by field, as neatflyx refuses
+// to publish it and the messaging package is not vendored here.
+func poisonEpisode(t *testing.T) []byte {
+	var payload proto.Buffer
+	payload.EncodeVarint(2<<3 | proto.WireVarint)
+	payload.EncodeVarint(1)
+	payload.EncodeVarint(3<<3 | proto.WireVarint)
+	payload.EncodeVarint(1)
+	payload.EncodeVarint(4<<3 | proto.WireBytes)
+	payload.EncodeStringBytes("https://neatflyx.example.com/unknown/s01e01")
+
+	var env proto.Buffer
+	env.EncodeVarint(1<<3 | proto.WireBytes)
+	env.EncodeStringBytes("application/x-protobuf")
+	env.EncodeVarint(2<<3 | proto.WireBytes)
+	env.EncodeStringBytes("mycodesmells.golangexamples.nats.streaming.proto.PublishEpisodeMessage")
+	env.EncodeVarint(3<<3 | proto.WireVarint)
+	env.EncodeVarint(1)
+	env.EncodeVarint(4<<3 | proto.WireBytes)
+	env.EncodeRawBytes(payload.Bytes())
+	return env.Bytes()
+}
+
+// Publishes data as is, bypassing validation of neatflyx.
+func publishRaw(t *testing.T, p *pipeline, subject string, data []byte) {
+	switch p.backend {
+	case "stan":
+		sc, err := stan.Connect("test-cluster", "integration-publisher", stan.NatsURL(p.nats.ClientURL()))
+		if err != nil {
+			t.Fatalf("Failed to connect to NATS Streaming: %v", err)
+		}
+		defer sc.Close()
+		if err := sc.Publish(subject, data); err != nil {
+			t.Fatalf("Failed to publish message: %v", err)
+		}
+	case "jetstream":
+		nc, err := nats.Connect(p.nats.ClientURL())
+		if err != nil {
+			t.Fatalf("Failed to connect to NATS: %v", err)
+		}
+		defer nc.Close()
+		js, err := nc.JetStream()
+		if err != nil {
+			t.Fatalf("Failed to create JetStream context: %v", err)
+		}
+		if _, err := js.Publish(subject, data); err != nil {
+			t.Fatalf("Failed to publish message: %v", err)
+		}
+	}
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			w := p.StartWatcher(t, "ACK_WAIT=1s", "MAX_DELIVERIES=2")
+			publishRaw(t, p, "episodes:publish", poisonEpisode(t))
+
+			// invalid, so moved without redelivery
+			w.WaitForOutput(t, "to 'episodes:publish.dlq' after 1 deliveries: missing series name", 1)
+			if out := w.Output(); strings.Contains(out, "failed (delivery") {
+				t.Errorf("Expected the poison message not to be redelivered:\n%s", out)
+			}
+
+			dlq := func(args ...string) string {
+				return bins.Run(t, "watcher", append([]string{"dlq"}, args...),
+					"NATS_ADDR="+p.nats.ClientURL(),
+					"BACKEND="+backend,
+				)
+			}
+
+			out := dlq("list", "-wait", "500ms")
+			if !strings.Contains(out, "1 dead letter(s) in 'episodes:publish.dlq'") || !strings.Contains(out, "reason:  missing series name") {
+				t.Fatalf("Expected the poison message to be listed, got: %s", out)
+			}
+
+			out = dlq("replay", "-all", "-wait", "500ms")
+			if !strings.Contains(out, "on 'episodes:publish'") {
+				t.Fatalf("Expected the poison message to be replayed, got: %s", out)
+			}
+
+			// still failing, so it ends up in the queue again
+			w.WaitForOutput(t, "to 'episodes:publish.dlq' after 1 deliveries", 2)
+			if out := dlq("list", "-wait", "500ms"); !strings.Contains(out, "2 dead letter(s)") {
+				t.Errorf("Expected replayed message to be moved again, got: %s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Package integration tests neatflyx and watcher together, against both
+// NATS Streaming and JetStream backends. Both are provided by a single
+// NATS Streaming server embedded in the test process, running on top of
+// NATS server with JetStream enabled.
+//
+// Tests build the services first, so they are guarded by integration tag:
+//
+//	go test -tags integration .
+package integration
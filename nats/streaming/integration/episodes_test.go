@@ -0,0 +1,365 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycodesmells/golang-examples/nats/servicetest"
+)
+
+// Publishes episode of a test series through neatflyx.
+func publishEpisode(t *testing.T, p *pipeline, episodeNo int) {
+	body := fmt.Sprintf(`{
+		"series_name": "Dark",
+		"season_no": 1,
+		"episode_no": %d,
+		"episode_url": "https://neatflyx.example.com/dark/s01e%02d"
+	}`, episodeNo, episodeNo)
+	resp, err := http.Post(p.url+"/publish", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, bs)
+	}
+}
+
+func watched(episodeNo int) string {
+	return fmt.Sprintf("Watching on S01E%02d of 'Dark'", episodeNo)
+}
+
+// Checks which of the episodes were watched, waiting for the expected ones
+// first, so that any unexpected ones had a chance to arrive too.
+func expectWatched(t *testing.T, w *servicetest.Service, expected []int, all int) {
+	for _, e := range expected {
+		w.WaitForOutput(t, watched(e), 1)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	isExpected := make(map[int]bool)
+	for _, e := range expected {
+		isExpected[e] = true
+	}
+	out := w.Output()
+	for e := 1; e <= all; e++ {
+		if !isExpected[e] && strings.Contains(out, watched(e)) {
+			t.Errorf("Expected episode %d not to be watched", e)
+		}
+	}
+}
+
+func TestPublishEpisode(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			w := p.StartWatcher(t)
+			publishEpisode(t, p, 1)
+			expectWatched(t, w, []int{1}, 1)
+		})
+	}
+}
+
+func TestStartPosition(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		startOpt string
+		expected []int
+	}{
+		{
+			desc:     "only new",
+			startOpt: "ONLY_NEW",
+			expected: []int{4},
+		},
+		{
+			desc:     "most recent",
+			startOpt: "MOST_RECENT",
+			expected: []int{3, 4},
+		},
+		{
+			desc:     "all",
+			startOpt: "ALL",
+			expected: []int{1, 2, 3, 4},
+		},
+		{
+			desc:     "at sequence",
+			startOpt: "SEQUENCE:2",
+			expected: []int{2, 3, 4},
+		},
+		{
+			desc:     "at time delta",
+			startOpt: "TIME_DELTA:1h",
+			expected: []int{1, 2, 3, 4},
+		},
+		{
+			desc:     "at time",
+			startOpt: "TIME:" + time.Now().Add(time.Hour).Format(time.RFC3339),
+			expected: nil,
+		},
+	}
+	for _, backend := range backends {
+		for _, tC := range testCases {
+			t.Run(backend+"/"+tC.desc, func(t *testing.T) {
+				p := startPipeline(t, backend)
+				defer p.Stop(t)
+
+				for e := 1; e <= 3; e++ {
+					publishEpisode(t, p, e)
+				}
+
+				if tC.expected == nil {
+					// start time in the future is rejected at startup
+					w := bins.Start(t, "watcher",
+						"NATS_ADDR="+p.nats.ClientURL(),
+						"BACKEND="+backend,
+						"START_OPT="+tC.startOpt,
+					)
+					defer w.Stop(t)
+					if exited, _ := w.Wait(servicetest.StartTimeout); !exited {
+						t.Fatal("Expected watcher to exit")
+					}
+					if !strings.Contains(w.Output(), "is in the future") {
+						t.Errorf("Expected error about start time, got: %s", w.Output())
+					}
+					return
+				}
+
+				w := p.StartWatcher(t, "START_OPT="+tC.startOpt)
+				publishEpisode(t, p, 4)
+				expectWatched(t, w, tC.expected, 4)
+			})
+		}
+	}
+}
+
+func TestDurableSubscription(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			env := []string{
+				"CLIENT_ID=watcher-durable",
+				"DURABLE_NAME=durable",
+				"START_OPT=ALL",
+			}
+			w := p.StartWatcher(t, env...)
+			publishEpisode(t, p, 1)
+			expectWatched(t, w, []int{1}, 1)
+			w.Stop(t)
+
+			// published while the watcher is down
+			publishEpisode(t, p, 2)
+
+			// resumes after the last acknowledged message, despite START_OPT
+			w = p.StartWatcher(t, env...)
+			publishEpisode(t, p, 3)
+			expectWatched(t, w, []int{2, 3}, 3)
+		})
+	}
+}
+
+func TestQueueGroup(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			env := []string{
+				"DURABLE_NAME=group",
+				"QUEUE_GROUP=watchers",
+			}
+			first := p.StartWatcher(t, env...)
+			second := p.StartWatcher(t, env...)
+
+			const episodes = 10
+			for e := 1; e <= episodes; e++ {
+				publishEpisode(t, p, e)
+			}
+
+			deadline := time.Now().Add(servicetest.WaitTimeout)
+			for {
+				var count int
+				for e := 1; e <= episodes; e++ {
+					inFirst := strings.Contains(first.Output(), watched(e))
+					inSecond := strings.Contains(second.Output(), watched(e))
+					if inFirst && inSecond {
+						t.Fatalf("Episode %d watched by both watchers", e)
+					}
+					if inFirst || inSecond {
+						count++
+					}
+				}
+				if count == episodes {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected %d episodes to be watched, got %d", episodes, count)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestInvalidConfigFailsFast(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		env      []string
+		expected string
+	}{
+		{
+			desc:     "unknown start option",
+			env:      []string{"START_OPT=YESTERDAY"},
+			expected: "unknown start option 'YESTERDAY'",
+		},
+		{
+			desc:     "invalid sequence",
+			env:      []string{"START_OPT=SEQUENCE:first"},
+			expected: "invalid sequence 'first'",
+		},
+		{
+			desc:     "invalid time delta",
+			env:      []string{"START_OPT=TIME_DELTA:-1h"},
+			expected: "invalid time delta '-1h'",
+		},
+		{
+			desc:     "unknown backend",
+			env:      []string{"BACKEND=kafka"},
+			expected: "unknown backend 'kafka'",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := bins.Start(t, "watcher", append([]string{"NATS_ADDR=nats://127.0.0.1:1"}, tC.env...)...)
+			defer w.Stop(t)
+
+			if exited, _ := w.Wait(servicetest.StartTimeout); !exited {
+				t.Fatal("Expected watcher to exit")
+			}
+			if out := w.Output(); !strings.Contains(out, tC.expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", tC.expected, out)
+			}
+		})
+	}
+}
+
+type publishResponse struct {
+	GUID     string            `json:"guid"`
+	Status   string            `json:"status"`
+	Stream   string            `json:"stream"`
+	Sequence uint64            `json:"sequence"`
+	Error    string            `json:"error"`
+	Fields   map[string]string `json:"fields"`
+}
+
+func postEpisode(t *testing.T, p *pipeline, query, body string) (int, publishResponse) {
+	resp, err := http.Post(p.url+"/publish"+query, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var pubResp publishResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pubResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, pubResp
+}
+
+func TestPublishValidation(t *testing.T) {
+	p := startPipeline(t, "stan")
+	defer p.Stop(t)
+
+	testCases := []struct {
+		desc  string
+		body  string
+		field string
+	}{
+		{
+			desc:  "missing series name",
+			body:  `{"season_no": 1, "episode_no": 1, "episode_url": "https://example.com/e1"}`,
+			field: "series_name",
+		},
+		{
+			desc:  "negative season",
+			body:  `{"series_name": "Dark", "season_no": -1, "episode_no": 1, "episode_url": "https://example.com/e1"}`,
+			field: "season_no",
+		},
+		{
+			desc:  "zero episode",
+			body:  `{"series_name": "Dark", "season_no": 1, "episode_url": "https://example.com/e1"}`,
+			field: "episode_no",
+		},
+		{
+			desc:  "missing URL",
+			body:  `{"series_name": "Dark", "season_no": 1, "episode_no": 1}`,
+			field: "episode_url",
+		},
+		{
+			desc:  "relative URL",
+			body:  `{"series_name": "Dark", "season_no": 1, "episode_no": 1, "episode_url": "/dark/e1"}`,
+			field: "episode_url",
+		},
+		{
+			desc:  "non-http URL",
+			body:  `{"series_name": "Dark", "season_no": 1, "episode_no": 1, "episode_url": "ftp://example.com/e1"}`,
+			field: "episode_url",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			code, resp := postEpisode(t, p, "", tC.body)
+			if code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", code)
+			}
+			if _, ok := resp.Fields[tC.field]; !ok || len(resp.Fields) != 1 {
+				t.Errorf("Expected error about '%s' only, got %v", tC.field, resp.Fields)
+			}
+		})
+	}
+}
+
+func TestPublishAcknowledgement(t *testing.T) {
+	body := `{"series_name": "Dark", "season_no": 1, "episode_no": 1, "episode_url": "https://example.com/e1"}`
+
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			p := startPipeline(t, backend)
+			defer p.Stop(t)
+
+			code, resp := postEpisode(t, p, "", body)
+			if code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %+v", code, resp)
+			}
+			if resp.GUID == "" || resp.Status != "published" {
+				t.Errorf("Expected published message with GUID, got %+v", resp)
+			}
+			// NATS Streaming does not report sequences of published messages
+			if backend == "jetstream" && (resp.Stream != "EPISODES" || resp.Sequence != 1) {
+				t.Errorf("Expected message #1 in EPISODES stream, got %+v", resp)
+			}
+
+			code, resp = postEpisode(t, p, "?async=true", body)
+			if code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d: %+v", code, resp)
+			}
+			if resp.GUID == "" || resp.Status != "pending" {
+				t.Errorf("Expected pending message with GUID, got %+v", resp)
+			}
+			p.neatflyx.WaitForOutput(t, "("+resp.GUID+")", 1)
+		})
+	}
+}
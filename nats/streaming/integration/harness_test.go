@@ -0,0 +1,131 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	stand "github.com/nats-io/nats-streaming-server/server"
+
+	"github.com/mycodesmells/golang-examples/nats/servicetest"
+)
+
+// Both backends are served by the same embedded server.
+var backends = []string{"stan", "jetstream"}
+
+// Services built once for all tests.
+var bins *servicetest.Binaries
+
+func TestMain(m *testing.M) {
+	b, err := servicetest.Build("neatflyx", "watcher", "catalog")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	bins = b
+
+	code := m.Run()
+	bins.Remove()
+	os.Exit(code)
+}
+
+// NATS Streaming server with in-memory store, running on top of NATS
+// server with JetStream enabled.
+type natsServer struct {
+	*stand.StanServer
+	storeDir string
+}
+
+func runNATS(t *testing.T) *natsServer {
+	storeDir, err := ioutil.TempDir("", "streaming-store")
+	if err != nil {
+		t.Fatalf("Failed to create store directory: %v", err)
+	}
+
+	nOpts := stand.DefaultNatsServerOptions
+	nOpts.Host = "127.0.0.1"
+	nOpts.Port = -1
+	nOpts.NoLog = true
+	nOpts.NoSigs = true
+	nOpts.JetStream = true
+	nOpts.StoreDir = storeDir
+
+	sOpts := stand.GetDefaultOptions()
+	sOpts.ID = "test-cluster"
+
+	s, err := stand.RunServerWithOpts(sOpts, &nOpts)
+	if err != nil {
+		os.RemoveAll(storeDir)
+		t.Fatalf("Failed to start NATS Streaming server: %v", err)
+	}
+	return &natsServer{StanServer: s, storeDir: storeDir}
+}
+
+func (s *natsServer) Shutdown() {
+	s.StanServer.Shutdown()
+	os.RemoveAll(s.storeDir)
+}
+
+// Embedded server with neatflyx publishing onto the selected backend.
+// Consumers are started by tests, as they need different options.
+type pipeline struct {
+	backend   string
+	nats      *natsServer
+	neatflyx  *servicetest.Service
+	url       string
+	consumers []*servicetest.Service
+}
+
+func startPipeline(t *testing.T, backend string) *pipeline {
+	p := &pipeline{
+		backend: backend,
+		nats:    runNATS(t),
+	}
+
+	addr := servicetest.FreeAddr(t)
+	p.url = "http://" + addr
+	p.neatflyx = bins.Start(t, "neatflyx",
+		"ADDR="+addr,
+		"NATS_ADDR="+p.nats.ClientURL(),
+		"BACKEND="+backend,
+	)
+	p.neatflyx.WaitForHTTP(t, addr)
+
+	return p
+}
+
+// Starts watcher and waits for its subscription.
+func (p *pipeline) StartWatcher(t *testing.T, env ...string) *servicetest.Service {
+	w := bins.Start(t, "watcher", append([]string{
+		"NATS_ADDR=" + p.nats.ClientURL(),
+		"BACKEND=" + p.backend,
+	}, env...)...)
+	p.consumers = append(p.consumers, w)
+	w.WaitForOutput(t, "Started subscription", 1)
+	return w
+}
+
+func (p *pipeline) Stop(t *testing.T) {
+	for _, c := range p.consumers {
+		c.Stop(t)
+	}
+	p.neatflyx.Stop(t)
+	p.nats.Shutdown()
+}
+
+// Starts catalog and waits for its HTTP server.
+func (p *pipeline) StartCatalog(t *testing.T, env ...string) (*servicetest.Service, string) {
+	addr := servicetest.FreeAddr(t)
+	c := bins.Start(t, "catalog", append([]string{
+		"ADDR=" + addr,
+		"NATS_ADDR=" + p.nats.ClientURL(),
+		"BACKEND=" + p.backend,
+	}, env...)...)
+	p.consumers = append(p.consumers, c)
+	c.WaitForHTTP(t, addr)
+	return c, "http://" + addr
+}
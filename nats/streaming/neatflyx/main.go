@@ -2,12 +2,15 @@ package main
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/kelseyhightower/envconfig"
-	stan "github.com/nats-io/go-nats-streaming"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging"
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
 )
 
 var (
@@ -17,6 +20,16 @@ var (
 type config struct {
 	Addr     string `envconfig:"ADDR" default:":8000"`
 	NatsAddr string `envconfig:"NATS_ADDR" default:"nats://localhost:4222"`
+
+	// Backend is either "stan" (NATS Streaming) or "jetstream". JetStream
+	// keeps episodes and their dead letters in Stream.
+	Backend string `envconfig:"BACKEND" default:"stan"`
+	Stream  string `envconfig:"STREAM" default:"EPISODES"`
+
+	// Episodes are published asynchronously, with at most MaxInFlight of
+	// them waiting for acknowledgement. Requests wait for AckTimeout.
+	MaxInFlight int           `envconfig:"MAX_IN_FLIGHT" default:"256"`
+	AckTimeout  time.Duration `envconfig:"ACK_TIMEOUT" default:"5s"`
 }
 
 func main() {
@@ -26,20 +39,31 @@ func main() {
 		log.Fatalf("Failed to load configuration from env: %v", err)
 	}
 
-	// Connect to NATS-Streaming
-	natsClient, err := stan.Connect(clusterID, uuid.NewV4().String(), stan.NatsURL(cfg.NatsAddr))
+	// Connect to NATS-Streaming or JetStream
+	natsClient, err := streaming.Connect(streaming.Config{
+		Backend:   cfg.Backend,
+		URL:       cfg.NatsAddr,
+		ClusterID: clusterID,
+		ClientID:  uuid.NewV4().String(),
+	})
 	if err != nil {
-		log.Fatalf("Can't connect: %v.\nMake sure a NATS Streaming Server is running at: %s", err, cfg.NatsAddr)
+		log.Fatalf("Can't connect: %v.\nMake sure a NATS Streaming Server (or NATS with JetStream) is running at: %s", err, cfg.NatsAddr)
 	}
 	defer natsClient.Close()
 
+	subject := messaging.PublishEpisode
+	if err := natsClient.EnsureStream(cfg.Stream, subject.Name, subject.DeadLetter().Name); err != nil {
+		log.Fatal(err)
+	}
+
 	srv := server{
-		natsClient: natsClient,
+		episodes:   streaming.NewAsyncPublisher(natsClient, subject, cfg.MaxInFlight),
+		ackTimeout: cfg.AckTimeout,
 	}
 
 	// Serve HTTP
 	r := mux.NewRouter()
-	r.HandleFunc("/publish", srv.HandlePublishEpisode)
+	r.HandleFunc("/publish", srv.HandlePublishEpisode).Methods(http.MethodPost)
 
 	log.Infof("Starting HTTP server on '%s'", cfg.Addr)
 	if err := http.ListenAndServe(cfg.Addr, r); err != nil {
@@ -2,14 +2,13 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/golang/protobuf/proto"
-	stan "github.com/nats-io/go-nats-streaming"
-	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
 	pb "github.com/mycodesmells/golang-examples/nats/streaming/proto"
 )
 
@@ -20,21 +19,44 @@ type publishRequest struct {
 	EpisodeURL string `json:"episode_url,omitempty"`
 }
 
+// Result of publishing, with sequence assigned to the message if the
+// backend reports it.
+type publishResponse struct {
+	GUID     string `json:"guid"`
+	Status   string `json:"status"`
+	Stream   string `json:"stream,omitempty"`
+	Sequence uint64 `json:"sequence,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
 const (
-	topicPublishEpisode = "episodes:publish"
+	statusPublished = "published"
+	statusPending   = "pending"
 )
 
 type server struct {
-	natsClient stan.Conn
+	episodes   *streaming.AsyncPublisher
+	ackTimeout time.Duration
 }
 
+// HandlePublishEpisode publishes the episode and waits for it to be stored,
+// unless async query parameter is set, in which case it responds with
+// 202 Accepted right away.
 func (s server) HandlePublishEpisode(rw http.ResponseWriter, req *http.Request) {
 	var pubReq publishRequest
 	if err := json.NewDecoder(req.Body).Decode(&pubReq); err != nil {
 		log.Errorf("Failed to read request: %v", err)
-		http.Error(rw, "Invalid request", http.StatusBadRequest)
+		writeError(rw, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if fields := pubReq.Validate(); fields != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{
+			Error:  "Invalid episode",
+			Fields: fields,
+		})
 		return
 	}
+	async, _ := strconv.ParseBool(req.URL.Query().Get("async"))
 
 	message := &pb.PublishEpisodeMessage{
 		SeriesName: pubReq.SeriesName,
@@ -43,25 +65,61 @@ func (s server) HandlePublishEpisode(rw http.ResponseWriter, req *http.Request)
 		EpisodeUrl: pubReq.EpisodeURL,
 	}
 
-	if err := s.publishMessage(topicPublishEpisode, message); err != nil {
+	acks := make(chan publishResponse, 1)
+	guid, err := s.episodes.Publish(message, func(ack streaming.PubAck, err error) {
+		resp := publishResponse{
+			GUID:     ack.GUID,
+			Status:   statusPublished,
+			Stream:   ack.Stream,
+			Sequence: ack.Sequence,
+		}
+		if err != nil {
+			log.Errorf("Failed to publish S%02dE%02d of '%s' (%s): %v", message.SeasonNo, message.EpisodeNo, message.SeriesName, ack.GUID, err)
+			resp.Error = err.Error()
+		} else {
+			log.Printf("Published S%02dE%02d of '%s' (%s)", message.SeasonNo, message.EpisodeNo, message.SeriesName, ack.GUID)
+		}
+		acks <- resp
+	})
+	if err == streaming.ErrTooManyInFlight {
+		rw.Header().Set("Retry-After", "1")
+		writeError(rw, http.StatusServiceUnavailable, "Too many episodes being published, try again later")
+		return
+	}
+	if err != nil {
 		log.Errorf("Failed to publish message onto queue: %v", err)
-		http.Error(rw, "", http.StatusInternalServerError)
+		writeError(rw, http.StatusInternalServerError, "Failed to publish episode")
 		return
 	}
 
-	log.Printf("Publishing on S%02dE%02d of '%s' on '%s'", message.SeasonNo, message.EpisodeNo, message.SeriesName, message.EpisodeUrl)
-	fmt.Fprint(rw, "Post publication is pending")
-}
-
-func (s server) publishMessage(topic string, msg proto.Message) error {
-	bs, err := proto.Marshal(msg)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal proto message")
+	log.Printf("Publishing S%02dE%02d of '%s' on '%s' (%s)", message.SeasonNo, message.EpisodeNo, message.SeriesName, message.EpisodeUrl, guid)
+	if async {
+		writeJSON(rw, http.StatusAccepted, publishResponse{GUID: guid, Status: statusPending})
+		return
 	}
 
-	if err := s.natsClient.Publish(topicPublishEpisode, bs); err != nil {
-		return errors.Wrap(err, "failed to publish message")
+	select {
+	case resp := <-acks:
+		if resp.Error != "" {
+			writeJSON(rw, http.StatusBadGateway, resp)
+			return
+		}
+		writeJSON(rw, http.StatusOK, resp)
+	case <-time.After(s.ackTimeout):
+		// the episode might still get published
+		writeJSON(rw, http.StatusGatewayTimeout, publishResponse{GUID: guid, Status: statusPending})
+	case <-req.Context().Done():
 	}
+}
 
-	return nil
+func writeError(rw http.ResponseWriter, code int, msg string) {
+	writeJSON(rw, code, errorResponse{Error: msg})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Errorf("Failed to write response: %v", err)
+	}
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxSeriesNameLength = 200
+
+// Error response, with details about invalid fields if there are any.
+type errorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Returns problems with request fields, keyed by their JSON names, or nil
+// if the request is valid.
+func (r publishRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+
+	switch {
+	case strings.TrimSpace(r.SeriesName) == "":
+		fields["series_name"] = "is required"
+	case utf8.RuneCountInString(r.SeriesName) > maxSeriesNameLength:
+		fields["series_name"] = "is too long"
+	case strings.TrimSpace(r.SeriesName) != r.SeriesName:
+		fields["series_name"] = "cannot start or end with whitespace"
+	}
+	if r.SeasonNo < 1 {
+		fields["season_no"] = "must be a positive number"
+	}
+	if r.EpisodeNo < 1 {
+		fields["episode_no"] = "must be a positive number"
+	}
+	if msg := validateEpisodeURL(r.EpisodeURL); msg != "" {
+		fields["episode_url"] = msg
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func validateEpisodeURL(s string) string {
+	if s == "" {
+		return "is required"
+	}
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "must be an absolute URL"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "must be an http or https URL"
+	}
+	return ""
+}
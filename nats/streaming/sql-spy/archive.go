@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/nats-io/go-nats-streaming/pb"
+	"github.com/pkg/errors"
+)
+
+// Formats of exported channels.
+const (
+	// One JSON record per line, with payload encoded in base64.
+	formatNDJSON = "ndjson"
+	// Records as pb.MsgProto messages, each prefixed with its size as
+	// varint, like protobuf's writeDelimitedTo does.
+	formatProto = "proto"
+)
+
+// Largest record accepted from a protobuf-delimited file, so that a corrupt
+// size prefix does not exhaust memory.
+const maxRecordSize = 64 << 20
+
+// Message of a channel as kept in exports, enough to publish it again.
+type record struct {
+	Sequence  uint64    `json:"sequence"`
+	Timestamp time.Time `json:"timestamp"`
+	Subject   string    `json:"subject"`
+	Data      []byte    `json:"data"`
+}
+
+type recordWriter interface {
+	Write(rec record) error
+	Flush() error
+}
+
+type recordReader interface {
+	// Read returns io.EOF once there are no records left.
+	Read() (record, error)
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case formatNDJSON:
+		return ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case formatProto:
+		return protoWriter{w: bw}, nil
+	}
+	return nil, unknownFormat(format)
+}
+
+func newRecordReader(format string, r io.Reader) (recordReader, error) {
+	br := bufio.NewReader(r)
+	switch format {
+	case formatNDJSON:
+		return ndjsonReader{dec: json.NewDecoder(br)}, nil
+	case formatProto:
+		return protoReader{r: br}, nil
+	}
+	return nil, unknownFormat(format)
+}
+
+func unknownFormat(format string) error {
+	return errors.Errorf("unknown export format '%s', expected %s or %s", format, formatNDJSON, formatProto)
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w ndjsonWriter) Write(rec record) error {
+	return w.enc.Encode(rec)
+}
+
+func (w ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (r ndjsonReader) Read() (record, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return rec, err
+		}
+		return rec, errors.Wrap(err, "invalid record")
+	}
+	return rec, nil
+}
+
+type protoWriter struct {
+	w *bufio.Writer
+}
+
+func (w protoWriter) Write(rec record) error {
+	msg := pb.MsgProto{
+		Sequence:  rec.Sequence,
+		Subject:   rec.Subject,
+		Data:      rec.Data,
+		Timestamp: rec.Timestamp.UnixNano(),
+	}
+	bs, err := msg.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal record")
+	}
+
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(bs)))
+	if _, err := w.w.Write(size[:n]); err != nil {
+		return err
+	}
+	_, err = w.w.Write(bs)
+	return err
+}
+
+func (w protoWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type protoReader struct {
+	r *bufio.Reader
+}
+
+func (r protoReader) Read() (record, error) {
+	size, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return record{}, err
+	}
+	if err != nil {
+		return record{}, errors.Wrap(err, "invalid record size")
+	}
+	if size > maxRecordSize {
+		return record{}, errors.Errorf("record of %d bytes is too big", size)
+	}
+
+	bs := make([]byte, size)
+	if _, err := io.ReadFull(r.r, bs); err != nil {
+		return record{}, errors.Wrap(err, "truncated record")
+	}
+	var msg pb.MsgProto
+	if err := msg.Unmarshal(bs); err != nil {
+		return record{}, errors.Wrap(err, "invalid record")
+	}
+	return record{
+		Sequence:  msg.Sequence,
+		Timestamp: time.Unix(0, msg.Timestamp).UTC(),
+		Subject:   msg.Subject,
+		Data:      msg.Data,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Runs the command with its arguments, writing results to out in the
+// given format.
+func run(spy inspector, out io.Writer, format, cmd string, args []string) error {
+	if format != "text" && format != "json" {
+		return errors.Errorf("unknown output format '%s', expected text or json", format)
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	var (
+		result interface{}
+		err    error
+	)
+	switch cmd {
+	case "serverinfo":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		result, err = spy.ServerInfo()
+	case "channels":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		result, err = spy.Channels()
+	case "subscriptions":
+		channelName := fs.String("channel", "", "list subscriptions of this channel only")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		result, err = spy.Subscriptions(*channelName)
+	case "clients":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		result, err = spy.Clients()
+	case "messages":
+		result, err = runMessages(spy, fs, args)
+	case "export":
+		summary, err := runExport(spy, out, fs, args)
+		if err != nil || summary == nil {
+			// exported messages were written to out already
+			return err
+		}
+		result = summary
+	case "import":
+		// report is written even if some messages failed to be published
+		report, err := runImport(fs, args)
+		if report != nil {
+			if err := writeResult(out, format, report); err != nil {
+				return err
+			}
+		}
+		return err
+	default:
+		return errors.Errorf("unknown command '%s', run 'sql-spy -h' to see available ones", cmd)
+	}
+	if err != nil {
+		return err
+	}
+	return writeResult(out, format, result)
+}
+
+func writeResult(out io.Writer, format string, result interface{}) error {
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	return writeText(out, result)
+}
+
+// Adds flags narrowing down messages to fs, returning function which
+// builds the filter once they are parsed.
+func filterFlags(fs *flag.FlagSet) func() (messageFilter, error) {
+	var (
+		f            messageFilter
+		since, until string
+	)
+	fs.StringVar(&f.Channel, "channel", "", "list messages of this channel only")
+	fs.Uint64Var(&f.FromSeq, "from-seq", 0, "skip messages with lower sequence")
+	fs.Uint64Var(&f.ToSeq, "to-seq", 0, "skip messages with higher sequence")
+	fs.StringVar(&since, "since", "", "skip messages stored before this time (RFC3339)")
+	fs.StringVar(&until, "until", "", "skip messages stored at or after this time (RFC3339)")
+	fs.IntVar(&f.Limit, "limit", 0, "list at most this many messages")
+
+	return func() (messageFilter, error) {
+		var err error
+		if f.Since, err = parseTime(since); err != nil {
+			return f, err
+		}
+		if f.Until, err = parseTime(until); err != nil {
+			return f, err
+		}
+		if f.Limit < 0 {
+			return f, errors.Errorf("invalid limit %d", f.Limit)
+		}
+		return f, nil
+	}
+}
+
+func runMessages(spy inspector, fs *flag.FlagSet, args []string) ([]message, error) {
+	var (
+		filter          = filterFlags(fs)
+		fallback        = fs.String("decoder", "envelope", "decoder of payloads: envelope, proto:<message type>, json, text or hex")
+		channelDecoders = newDecoders(nil)
+	)
+	fs.Var(channelDecoders, "channel-decoder", "decoder of payloads on a single channel, as channel=decoder (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	f, err := filter()
+	if err != nil {
+		return nil, err
+	}
+	if channelDecoders.fallback, err = parseDecoder(*fallback); err != nil {
+		return nil, err
+	}
+
+	return spy.Messages(f, channelDecoders)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time '%s', expected RFC3339 format", s)
+	}
+	return t, nil
+}
+
+func writeText(out io.Writer, result interface{}) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	switch r := result.(type) {
+	case serverInfo:
+		fmt.Fprintf(tw, "Cluster ID:\t%s\n", r.ClusterID)
+		if r.NodeID != "" {
+			fmt.Fprintf(tw, "Node ID:\t%s\n", r.NodeID)
+		}
+		fmt.Fprintf(tw, "Version:\t%d\n", r.Version)
+		fmt.Fprintf(tw, "Discovery:\t%s\n", r.Discovery)
+		fmt.Fprintf(tw, "Publish:\t%s\n", r.Publish)
+		fmt.Fprintf(tw, "Subscribe:\t%s\n", r.Subscribe)
+		fmt.Fprintf(tw, "Unsubscribe:\t%s\n", r.Unsubscribe)
+		fmt.Fprintf(tw, "Close:\t%s\n", r.Close)
+		fmt.Fprintf(tw, "Sub close:\t%s\n", r.SubClose)
+		fmt.Fprintf(tw, "Acks subs:\t%s\n", r.AcksSubs)
+	case []channel:
+		fmt.Fprintln(tw, "ID\tNAME\tMESSAGES\tFIRST\tLAST\tMAX MSGS\tMAX BYTES\tMAX AGE\tDELETED")
+		for _, c := range r {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%t\n", c.ID, c.Name, c.Messages, c.FirstSequence, c.LastSequence, c.MaxMsgs, c.MaxBytes, c.MaxAge, c.Deleted)
+		}
+	case []subscription:
+		fmt.Fprintln(tw, "CHANNEL\tID\tCLIENT\tDURABLE\tQUEUE\tLAST SENT\tMAX IN FLIGHT\tACK WAIT\tCLOSED\tDELETED")
+		for _, s := range r {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%d\t%s\t%t\t%t\n", s.Channel, s.ID, s.ClientID, s.DurableName, s.QueueGroup, s.LastSent, s.MaxInFlight, s.AckWait, s.Closed, s.Deleted)
+		}
+	case []client:
+		fmt.Fprintln(tw, "ID\tHEARTBEAT INBOX")
+		for _, c := range r {
+			fmt.Fprintf(tw, "%s\t%s\n", c.ID, c.HbInbox)
+		}
+	case []message:
+		for _, m := range r {
+			writeMessage(tw, m)
+		}
+	case *exportSummary:
+		fmt.Fprintf(tw, "Exported %d messages of '%s' (#%d-#%d) to %s as %s\n", r.Messages, r.Channel, r.FirstSequence, r.LastSequence, r.File, r.Format)
+	case *importReport:
+		fmt.Fprintln(tw, "SOURCE SEQ\tSUBJECT\tTARGET SEQ\tGUID\tERROR")
+		for _, m := range r.Messages {
+			targetSeq := "-"
+			if m.TargetSequence > 0 {
+				targetSeq = fmt.Sprintf("%s:%d", m.Stream, m.TargetSequence)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", m.SourceSequence, m.Subject, targetSeq, m.GUID, m.Error)
+		}
+		fmt.Fprintf(tw, "Published %d messages, %d failed\n", r.Published, r.Failed)
+	default:
+		return errors.Errorf("cannot write %T as text", result)
+	}
+	return tw.Flush()
+}
+
+func writeMessage(w io.Writer, m message) {
+	fmt.Fprintf(w, "%s #%d\n", m.Channel, m.Sequence)
+	fmt.Fprintf(w, "  Timestamp:\t%s\n", m.Timestamp.Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "  Size:\t%d\n", m.Size)
+	if m.Redelivered {
+		fmt.Fprintf(w, "  Redelivered:\t%t\n", m.Redelivered)
+	}
+
+	p := m.Payload
+	if p.Error != "" {
+		fmt.Fprintf(w, "  Error:\t%s\n", p.Error)
+		fmt.Fprintf(w, "  Raw:\t%s\n", p.Raw)
+		return
+	}
+	if p.SchemaVersion > 0 {
+		fmt.Fprintf(w, "  Type:\t%s (v%d)\n", p.Type, p.SchemaVersion)
+	} else {
+		fmt.Fprintf(w, "  Type:\t%s\n", p.Type)
+	}
+	switch v := p.Value.(type) {
+	case proto.Message:
+		fmt.Fprintf(w, "  Message:\t%s\n", proto.CompactTextString(v))
+	case json.RawMessage:
+		fmt.Fprintf(w, "  Message:\t%s\n", v)
+	default:
+		fmt.Fprintf(w, "  Message:\t%v\n", v)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	mpb "github.com/mycodesmells/golang-examples/nats/messaging/proto"
+	// registers message types published by the services
+	_ "github.com/mycodesmells/golang-examples/nats/streaming/proto"
+)
+
+const contentTypeProtobuf = "application/x-protobuf"
+
+// Decoded message payload. Payloads which could not be decoded are kept
+// as hex along with the reason.
+type payload struct {
+	Type          string      `json:"type,omitempty"`
+	SchemaVersion uint32      `json:"schema_version,omitempty"`
+	Value         interface{} `json:"value,omitempty"`
+	Raw           string      `json:"raw,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
+
+func failedPayload(data []byte, err error) payload {
+	return payload{
+		Raw:   hex.EncodeToString(data),
+		Error: err.Error(),
+	}
+}
+
+type decoder func(data []byte) (payload, error)
+
+var decoderNames = []string{"envelope", "proto:<message type>", "json", "text", "hex"}
+
+func parseDecoder(name string) (decoder, error) {
+	switch {
+	case name == "envelope":
+		return decodeEnvelope, nil
+	case name == "json":
+		return decodeJSON, nil
+	case name == "text":
+		return decodeText, nil
+	case name == "hex":
+		return decodeHex, nil
+	case strings.HasPrefix(name, "proto:"):
+		messageType := strings.TrimPrefix(name, "proto:")
+		if proto.MessageType(messageType) == nil {
+			return nil, errors.Errorf("unknown message type '%s'", messageType)
+		}
+		return func(data []byte) (payload, error) {
+			return decodeProto(messageType, data)
+		}, nil
+	}
+	return nil, errors.Errorf("unknown decoder '%s', expected one of: %s", name, strings.Join(decoderNames, ", "))
+}
+
+// Unwraps the envelope the services put every message in, and decodes its
+// payload as the message type it names.
+func decodeEnvelope(data []byte) (payload, error) {
+	var env mpb.Envelope
+	if err := proto.Unmarshal(data, &env); err != nil {
+		return payload{}, errors.Wrap(err, "invalid envelope")
+	}
+	if env.MessageType == "" {
+		return payload{}, errors.New("invalid envelope, message type is missing")
+	}
+	if env.ContentType != contentTypeProtobuf {
+		return payload{}, errors.Errorf("unsupported content type '%s'", env.ContentType)
+	}
+
+	p, err := decodeProto(env.MessageType, env.Payload)
+	if err != nil {
+		return payload{}, err
+	}
+	p.SchemaVersion = env.SchemaVersion
+	return p, nil
+}
+
+// Decodes data as a protobuf message of a type registered by generated code.
+func decodeProto(messageType string, data []byte) (payload, error) {
+	t := proto.MessageType(messageType)
+	if t == nil {
+		return payload{}, errors.Errorf("unknown message type '%s'", messageType)
+	}
+	msg := reflect.New(t.Elem()).Interface().(proto.Message)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return payload{}, errors.Wrapf(err, "invalid %s", messageType)
+	}
+	return payload{Type: messageType, Value: msg}, nil
+}
+
+func decodeJSON(data []byte) (payload, error) {
+	if !json.Valid(data) {
+		return payload{}, errors.New("invalid JSON")
+	}
+	return payload{Type: "json", Value: json.RawMessage(data)}, nil
+}
+
+func decodeText(data []byte) (payload, error) {
+	if !utf8.Valid(data) {
+		return payload{}, errors.New("invalid UTF-8 text")
+	}
+	return payload{Type: "text", Value: string(data)}, nil
+}
+
+func decodeHex(data []byte) (payload, error) {
+	return payload{Type: "hex", Value: hex.EncodeToString(data)}, nil
+}
+
+// Decoders picked by channel name, falling back to the default one for
+// channels without their own. Set from flags in 'channel=decoder' form.
+type decoders struct {
+	byChannel map[string]decoder
+	names     map[string]string
+	fallback  decoder
+}
+
+func newDecoders(fallback decoder) *decoders {
+	return &decoders{
+		byChannel: make(map[string]decoder),
+		names:     make(map[string]string),
+		fallback:  fallback,
+	}
+}
+
+func (d *decoders) Decode(channel string, data []byte) payload {
+	dec, ok := d.byChannel[channel]
+	if !ok {
+		dec = d.fallback
+	}
+	p, err := dec(data)
+	if err != nil {
+		return failedPayload(data, err)
+	}
+	return p
+}
+
+func (d *decoders) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.Errorf("invalid decoder '%s', expected channel=decoder", s)
+	}
+	dec, err := parseDecoder(parts[1])
+	if err != nil {
+		return err
+	}
+	d.byChannel[parts[0]] = dec
+	d.names[parts[0]] = parts[1]
+	return nil
+}
+
+func (d *decoders) String() string {
+	if d == nil {
+		return ""
+	}
+	var pairs []string
+	for channel, name := range d.names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", channel, name))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/go-nats-streaming/pb"
+	"github.com/nats-io/nats-streaming-server/spb"
+	"github.com/pkg/errors"
+)
+
+type serverInfo struct {
+	ClusterID   string `json:"cluster_id"`
+	NodeID      string `json:"node_id,omitempty"`
+	Version     int    `json:"version"`
+	Discovery   string `json:"discovery"`
+	Publish     string `json:"publish"`
+	Subscribe   string `json:"subscribe"`
+	Unsubscribe string `json:"unsubscribe"`
+	Close       string `json:"close"`
+	SubClose    string `json:"sub_close"`
+	AcksSubs    string `json:"acks_subs"`
+}
+
+type channel struct {
+	ID            int64         `json:"id"`
+	Name          string        `json:"name"`
+	Messages      int64         `json:"messages"`
+	FirstSequence uint64        `json:"first_sequence"`
+	LastSequence  uint64        `json:"last_sequence"`
+	MaxMsgs       int64         `json:"max_msgs"`
+	MaxBytes      int64         `json:"max_bytes"`
+	MaxAge        time.Duration `json:"max_age"`
+	Deleted       bool          `json:"deleted"`
+}
+
+type subscription struct {
+	Channel     string        `json:"channel"`
+	ID          uint64        `json:"id"`
+	ClientID    string        `json:"client_id"`
+	DurableName string        `json:"durable_name,omitempty"`
+	QueueGroup  string        `json:"queue_group,omitempty"`
+	Inbox       string        `json:"inbox"`
+	AckInbox    string        `json:"ack_inbox"`
+	MaxInFlight int32         `json:"max_in_flight"`
+	AckWait     time.Duration `json:"ack_wait"`
+	LastSent    uint64        `json:"last_sent"`
+	Closed      bool          `json:"closed"`
+	Deleted     bool          `json:"deleted"`
+}
+
+type client struct {
+	ID      string `json:"id"`
+	HbInbox string `json:"hb_inbox"`
+}
+
+type message struct {
+	Channel     string    `json:"channel"`
+	Sequence    uint64    `json:"sequence"`
+	Timestamp   time.Time `json:"timestamp"`
+	Size        int       `json:"size"`
+	Redelivered bool      `json:"redelivered,omitempty"`
+	Payload     payload   `json:"payload"`
+}
+
+// Narrows down listed messages, zero values meaning no restriction.
+type messageFilter struct {
+	Channel string
+	FromSeq uint64
+	ToSeq   uint64
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// Reads NATS Streaming objects straight from the tables of its SQL store.
+type inspector struct {
+	db     *sql.DB
+	driver string
+}
+
+func newInspector(db *sql.DB, driver string) inspector {
+	return inspector{db: db, driver: driver}
+}
+
+func (i inspector) ServerInfo() (serverInfo, error) {
+	var (
+		info  serverInfo
+		data  []byte
+		sinfo spb.ServerInfo
+	)
+	err := i.db.QueryRow("SELECT id, proto, version FROM ServerInfo").Scan(&info.ClusterID, &data, &info.Version)
+	if err == sql.ErrNoRows {
+		return info, errors.New("server info not found, store was never used")
+	}
+	if err != nil {
+		return info, errors.Wrap(err, "failed to read server info")
+	}
+	if err := sinfo.Unmarshal(data); err != nil {
+		return info, errors.Wrap(err, "failed to decode server info")
+	}
+
+	info.NodeID = sinfo.NodeID
+	info.Discovery = sinfo.Discovery
+	info.Publish = sinfo.Publish
+	info.Subscribe = sinfo.Subscribe
+	info.Unsubscribe = sinfo.Unsubscribe
+	info.Close = sinfo.Close
+	info.SubClose = sinfo.SubClose
+	info.AcksSubs = sinfo.AcksSubs
+	return info, nil
+}
+
+func (i inspector) Channels() ([]channel, error) {
+	rows, err := i.db.Query(`SELECT c.id, c.name, c.maxmsgs, c.maxbytes, c.maxage, c.deleted,
+		COALESCE(m.count, 0), COALESCE(m.first, 0), COALESCE(m.last, 0)
+		FROM Channels c
+		LEFT JOIN (SELECT id, COUNT(*) AS count, MIN(seq) AS first, MAX(seq) AS last FROM Messages GROUP BY id) m ON m.id = c.id
+		ORDER BY c.name`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query channels")
+	}
+	defer rows.Close()
+
+	channels := []channel{}
+	for rows.Next() {
+		var (
+			c      channel
+			maxAge int64
+		)
+		if err := rows.Scan(&c.ID, &c.Name, &c.MaxMsgs, &c.MaxBytes, &maxAge, &c.Deleted, &c.Messages, &c.FirstSequence, &c.LastSequence); err != nil {
+			return nil, errors.Wrap(err, "failed to read channel")
+		}
+		c.MaxAge = time.Duration(maxAge)
+		channels = append(channels, c)
+	}
+	return channels, errors.Wrap(rows.Err(), "failed to read channels")
+}
+
+// Subscriptions lists subscriptions of a channel, or of all of them if
+// channelName is empty.
+func (i inspector) Subscriptions(channelName string) ([]subscription, error) {
+	q := i.newQuery(`SELECT c.name, s.subid, s.lastsent, s.deleted, s.proto
+		FROM Subscriptions s JOIN Channels c ON c.id = s.id`)
+	if channelName != "" {
+		q.Where("c.name = ?", channelName)
+	}
+	q.Append("ORDER BY c.name, s.subid")
+
+	rows, err := i.db.Query(q.String(), q.args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query subscriptions")
+	}
+	defer rows.Close()
+
+	subs := []subscription{}
+	for rows.Next() {
+		var (
+			sub   subscription
+			data  []byte
+			state spb.SubState
+		)
+		if err := rows.Scan(&sub.Channel, &sub.ID, &sub.LastSent, &sub.Deleted, &data); err != nil {
+			return nil, errors.Wrap(err, "failed to read subscription")
+		}
+		if err := state.Unmarshal(data); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode subscription %d of '%s'", sub.ID, sub.Channel)
+		}
+
+		sub.ClientID = state.ClientID
+		sub.DurableName = state.DurableName
+		sub.QueueGroup = state.QGroup
+		sub.Inbox = state.Inbox
+		sub.AckInbox = state.AckInbox
+		sub.MaxInFlight = state.MaxInFlight
+		sub.AckWait = time.Duration(state.AckWaitInSecs) * time.Second
+		sub.Closed = state.IsClosed
+		subs = append(subs, sub)
+	}
+	return subs, errors.Wrap(rows.Err(), "failed to read subscriptions")
+}
+
+func (i inspector) Clients() ([]client, error) {
+	rows, err := i.db.Query("SELECT id, hbinbox FROM Clients ORDER BY id")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query clients")
+	}
+	defer rows.Close()
+
+	clients := []client{}
+	for rows.Next() {
+		var c client
+		if err := rows.Scan(&c.ID, &c.HbInbox); err != nil {
+			return nil, errors.Wrap(err, "failed to read client")
+		}
+		clients = append(clients, c)
+	}
+	return clients, errors.Wrap(rows.Err(), "failed to read clients")
+}
+
+// Message as stored by the server, with the payload published by the
+// client inside of Data, a marshaled pb.MsgProto.
+type storedMessage struct {
+	Channel   string
+	Sequence  uint64
+	Timestamp time.Time
+	Size      int
+	Data      []byte
+}
+
+// EachMessage calls fn with messages matching the filter, ordered by
+// channel and sequence, stopping at the first error.
+func (i inspector) EachMessage(f messageFilter, fn func(storedMessage) error) error {
+	q := i.newQuery(`SELECT c.name, m.seq, m.timestamp, m.size, m.data
+		FROM Messages m JOIN Channels c ON c.id = m.id`)
+	if f.Channel != "" {
+		q.Where("c.name = ?", f.Channel)
+	}
+	if f.FromSeq > 0 {
+		q.Where("m.seq >= ?", f.FromSeq)
+	}
+	if f.ToSeq > 0 {
+		q.Where("m.seq <= ?", f.ToSeq)
+	}
+	if !f.Since.IsZero() {
+		q.Where("m.timestamp >= ?", f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		q.Where("m.timestamp < ?", f.Until.UnixNano())
+	}
+	q.Append("ORDER BY c.name, m.seq")
+	if f.Limit > 0 {
+		q.Append(fmt.Sprintf("LIMIT %d", f.Limit))
+	}
+
+	rows, err := i.db.Query(q.String(), q.args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to query messages")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			msg       storedMessage
+			timestamp int64
+		)
+		if err := rows.Scan(&msg.Channel, &msg.Sequence, &timestamp, &msg.Size, &msg.Data); err != nil {
+			return errors.Wrap(err, "failed to read message")
+		}
+		msg.Timestamp = time.Unix(0, timestamp).UTC()
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(rows.Err(), "failed to read messages")
+}
+
+// Messages lists messages matching the filter, with their payloads decoded
+// by decoders of their channels.
+func (i inspector) Messages(f messageFilter, decoders *decoders) ([]message, error) {
+	msgs := []message{}
+	err := i.EachMessage(f, func(stored storedMessage) error {
+		msg := message{
+			Channel:   stored.Channel,
+			Sequence:  stored.Sequence,
+			Timestamp: stored.Timestamp,
+			Size:      stored.Size,
+		}
+
+		var msgProto pb.MsgProto
+		if err := msgProto.Unmarshal(stored.Data); err != nil {
+			msg.Payload = failedPayload(stored.Data, errors.Wrap(err, "invalid message"))
+		} else {
+			msg.Redelivered = msgProto.Redelivered
+			msg.Payload = decoders.Decode(msg.Channel, msgProto.Data)
+		}
+		msgs = append(msgs, msg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Builds a query with conditions written with '?' placeholders, replacing
+// them with ones the driver understands.
+type query struct {
+	driver string
+	sql    string
+	conds  []string
+	suffix []string
+	args   []interface{}
+}
+
+func (i inspector) newQuery(sql string) *query {
+	return &query{driver: i.driver, sql: sql}
+}
+
+func (q *query) Where(cond string, arg interface{}) {
+	q.args = append(q.args, arg)
+	if q.driver == "postgres" {
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(q.args)), 1)
+	}
+	q.conds = append(q.conds, cond)
+}
+
+func (q *query) Append(clause string) {
+	q.suffix = append(q.suffix, clause)
+}
+
+func (q *query) String() string {
+	s := q.sql
+	if len(q.conds) > 0 {
+		s += " WHERE " + strings.Join(q.conds, " AND ")
+	}
+	if len(q.suffix) > 0 {
+		s += " " + strings.Join(q.suffix, " ")
+	}
+	return s
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/nats-io/go-nats-streaming/pb"
+	"github.com/nats-io/nats-streaming-server/spb"
+
+	mpb "github.com/mycodesmells/golang-examples/nats/messaging/proto"
+	mypb "github.com/mycodesmells/golang-examples/nats/streaming/proto"
+)
+
+const (
+	episodeType    = "mycodesmells.golangexamples.nats.streaming.proto.PublishEpisodeMessage"
+	deadLetterType = "mycodesmells.golangexamples.nats.messaging.proto.DeadLetter"
+	envelopeType   = "mycodesmells.golangexamples.nats.messaging.proto.Envelope"
+)
+
+// Tables of the SQL store, as created by NATS Streaming server.
+var schema = []string{
+	"CREATE TABLE ServerInfo (uniquerow INTEGER DEFAULT 1, id VARCHAR(1024), proto BYTEA, version INTEGER, PRIMARY KEY (uniquerow))",
+	"CREATE TABLE Clients (id VARCHAR(1024), hbinbox TEXT, PRIMARY KEY (id))",
+	"CREATE TABLE Channels (id INTEGER, name VARCHAR(1024) NOT NULL, maxseq BIGINT DEFAULT 0, maxmsgs INTEGER DEFAULT 0, maxbytes BIGINT DEFAULT 0, maxage BIGINT DEFAULT 0, deleted BOOL DEFAULT FALSE, PRIMARY KEY (id))",
+	"CREATE TABLE Messages (id INTEGER, seq BIGINT, timestamp BIGINT, size INTEGER, data BYTEA, CONSTRAINT PK_MsgKey PRIMARY KEY(id, seq))",
+	"CREATE TABLE Subscriptions (id INTEGER, subid BIGINT, lastsent BIGINT DEFAULT 0, proto BYTEA, deleted BOOL DEFAULT FALSE, CONSTRAINT PK_SubKey PRIMARY KEY(id, subid))",
+}
+
+var stored = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func marshal(t *testing.T, msg proto.Message) []byte {
+	bs, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal %T: %v", msg, err)
+	}
+	return bs
+}
+
+func envelope(t *testing.T, messageType string, msg proto.Message) []byte {
+	return marshal(t, &mpb.Envelope{
+		ContentType:   contentTypeProtobuf,
+		MessageType:   messageType,
+		SchemaVersion: 1,
+		Payload:       marshal(t, msg),
+	})
+}
+
+func episode(no int64) *mypb.PublishEpisodeMessage {
+	return &mypb.PublishEpisodeMessage{
+		SeriesName: "Dark",
+		SeasonNo:   1,
+		EpisodeNo:  no,
+		EpisodeUrl: fmt.Sprintf("https://example.com/dark/s01e%02d", no),
+	}
+}
+
+// Opens SQLite store filled with a cluster having a few channels, with
+// messages stored an hour apart.
+func openFixture(t *testing.T) (inspector, func()) {
+	dir, err := ioutil.TempDir("", "sql-spy")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "store.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+
+	exec := func(query string, args ...interface{}) {
+		if _, err := db.Exec(query, args...); err != nil {
+			cleanup()
+			t.Fatalf("Failed to prepare fixture: %v", err)
+		}
+	}
+	for _, table := range schema {
+		exec(table)
+	}
+
+	exec("INSERT INTO ServerInfo (id, proto, version) VALUES (?, ?, 1)", "test-cluster", marshal(t, &spb.ServerInfo{
+		ClusterID: "test-cluster",
+		Discovery: "_STAN.discover.test-cluster",
+		Publish:   "_STAN.pub.cluster",
+		Subscribe: "_STAN.sub.cluster",
+	}))
+	exec("INSERT INTO Clients (id, hbinbox) VALUES ('watcher-binge', '_INBOX.hb1'), ('neatflyx', '_INBOX.hb2')")
+	exec(`INSERT INTO Channels (id, name, maxmsgs, maxage, deleted) VALUES
+		(1, 'episodes', 1000, 0, 0),
+		(2, 'episodes.dlq', 0, 86400000000000, 0),
+		(3, 'raw', 0, 0, 0),
+		(4, 'old', 0, 0, 1)`)
+
+	var seq uint64
+	addMessage := func(channelID int, channel string, data []byte) {
+		seq++
+		timestamp := stored.Add(time.Duration(seq) * time.Hour).UnixNano()
+		msg := marshal(t, &pb.MsgProto{Sequence: seq, Subject: channel, Data: data, Timestamp: timestamp})
+		exec("INSERT INTO Messages (id, seq, timestamp, size, data) VALUES (?, ?, ?, ?, ?)", channelID, seq, timestamp, len(data), msg)
+	}
+	for e := int64(1); e <= 3; e++ {
+		addMessage(1, "episodes", envelope(t, episodeType, episode(e)))
+	}
+	addMessage(2, "episodes.dlq", envelope(t, deadLetterType, &mpb.DeadLetter{Subject: "episodes", Sequence: 2, Reason: "failed"}))
+	addMessage(3, "raw", []byte(`{"hello": "world"}`))
+	addMessage(3, "raw", []byte{0xff, 0x00})
+
+	exec("INSERT INTO Subscriptions (id, subid, lastsent, proto) VALUES (?, 1, 3, ?), (?, 2, 1, ?)",
+		1, marshal(t, &spb.SubState{ID: 1, ClientID: "watcher-binge", DurableName: "binge", MaxInFlight: 1024, AckWaitInSecs: 30, IsDurable: true}),
+		2, marshal(t, &spb.SubState{ID: 2, ClientID: "watcher-binge", QGroup: "dlq", IsClosed: true}),
+	)
+
+	return newInspector(db, "sqlite3"), cleanup
+}
+
+func TestMessagesFilter(t *testing.T) {
+	spy, cleanup := openFixture(t)
+	defer cleanup()
+
+	testCases := []struct {
+		desc     string
+		filter   messageFilter
+		expected []string
+	}{
+		{
+			desc:     "all",
+			expected: []string{"episodes#1", "episodes#2", "episodes#3", "episodes.dlq#4", "raw#5", "raw#6"},
+		},
+		{
+			desc:     "channel",
+			filter:   messageFilter{Channel: "episodes"},
+			expected: []string{"episodes#1", "episodes#2", "episodes#3"},
+		},
+		{
+			desc:     "unknown channel",
+			filter:   messageFilter{Channel: "movies"},
+			expected: nil,
+		},
+		{
+			desc:     "sequence range",
+			filter:   messageFilter{FromSeq: 2, ToSeq: 4},
+			expected: []string{"episodes#2", "episodes#3", "episodes.dlq#4"},
+		},
+		{
+			desc:     "time range",
+			filter:   messageFilter{Since: stored.Add(3 * time.Hour), Until: stored.Add(5 * time.Hour)},
+			expected: []string{"episodes#3", "episodes.dlq#4"},
+		},
+		{
+			desc:     "limit",
+			filter:   messageFilter{Channel: "episodes", FromSeq: 2, Limit: 1},
+			expected: []string{"episodes#2"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			msgs, err := spy.Messages(tC.filter, newDecoders(decodeHex))
+			if err != nil {
+				t.Fatalf("Failed to list messages: %v", err)
+			}
+			var got []string
+			for _, m := range msgs {
+				got = append(got, fmt.Sprintf("%s#%d", m.Channel, m.Sequence))
+			}
+			if !reflect.DeepEqual(got, tC.expected) {
+				t.Errorf("Expected messages %v, got %v", tC.expected, got)
+			}
+		})
+	}
+}
+
+func TestMessagesDecode(t *testing.T) {
+	spy, cleanup := openFixture(t)
+	defer cleanup()
+
+	testCases := []struct {
+		desc     string
+		fallback string
+		channels []string
+		seq      uint64
+		expected payload
+		errorMsg string
+	}{
+		{
+			desc:     "envelope",
+			fallback: "envelope",
+			seq:      2,
+			expected: payload{Type: episodeType, SchemaVersion: 1, Value: episode(2)},
+		},
+		{
+			desc:     "envelope of dead letter",
+			fallback: "envelope",
+			seq:      4,
+			expected: payload{Type: deadLetterType, SchemaVersion: 1, Value: &mpb.DeadLetter{Subject: "episodes", Sequence: 2, Reason: "failed"}},
+		},
+		{
+			desc:     "not an envelope",
+			fallback: "envelope",
+			seq:      5,
+			errorMsg: "invalid envelope",
+		},
+		{
+			desc:     "JSON by channel",
+			fallback: "envelope",
+			channels: []string{"raw=json"},
+			seq:      5,
+			expected: payload{Type: "json", Value: json.RawMessage(`{"hello": "world"}`)},
+		},
+		{
+			desc:     "invalid JSON",
+			fallback: "json",
+			seq:      6,
+			errorMsg: "invalid JSON",
+		},
+		{
+			desc:     "text",
+			fallback: "text",
+			seq:      5,
+			expected: payload{Type: "text", Value: `{"hello": "world"}`},
+		},
+		{
+			desc:     "hex",
+			fallback: "envelope",
+			channels: []string{"raw=hex", "episodes=json"},
+			seq:      6,
+			expected: payload{Type: "hex", Value: "ff00"},
+		},
+		{
+			desc:     "bare proto",
+			fallback: "envelope",
+			channels: []string{"episodes=proto:" + envelopeType},
+			seq:      1,
+			expected: payload{Type: envelopeType, Value: &mpb.Envelope{
+				ContentType:   contentTypeProtobuf,
+				MessageType:   episodeType,
+				SchemaVersion: 1,
+				Payload:       marshal(t, episode(1)),
+			}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			fallback, err := parseDecoder(tC.fallback)
+			if err != nil {
+				t.Fatalf("Failed to parse decoder: %v", err)
+			}
+			decoders := newDecoders(fallback)
+			for _, c := range tC.channels {
+				if err := decoders.Set(c); err != nil {
+					t.Fatalf("Failed to set decoder: %v", err)
+				}
+			}
+
+			msgs, err := spy.Messages(messageFilter{FromSeq: tC.seq, ToSeq: tC.seq}, decoders)
+			if err != nil {
+				t.Fatalf("Failed to list messages: %v", err)
+			}
+			if len(msgs) != 1 {
+				t.Fatalf("Expected a single message, got %d", len(msgs))
+			}
+			got := msgs[0].Payload
+
+			if tC.errorMsg != "" {
+				if !strings.Contains(got.Error, tC.errorMsg) || got.Raw == "" {
+					t.Errorf("Expected error '%s' with raw data, got %+v", tC.errorMsg, got)
+				}
+				return
+			}
+			if got.Error != "" {
+				t.Fatalf("Expected payload to be decoded, got error: %s", got.Error)
+			}
+			if !reflect.DeepEqual(got, tC.expected) {
+				t.Errorf("Expected payload %+v, got %+v", tC.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseDecoder(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		errorMsg string
+	}{
+		{
+			desc:  "proto of registered type",
+			value: "episodes=proto:" + episodeType,
+		},
+		{
+			desc:     "proto of unknown type",
+			value:    "episodes=proto:Episode",
+			errorMsg: "unknown message type 'Episode'",
+		},
+		{
+			desc:     "unknown decoder",
+			value:    "episodes=xml",
+			errorMsg: "unknown decoder 'xml'",
+		},
+		{
+			desc:     "missing channel",
+			value:    "json",
+			errorMsg: "expected channel=decoder",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := newDecoders(decodeHex).Set(tC.value)
+			if tC.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tC.errorMsg != "" && (err == nil || !strings.Contains(err.Error(), tC.errorMsg)) {
+				t.Errorf("Expected error '%s', got: %v", tC.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	spy, cleanup := openFixture(t)
+	defer cleanup()
+
+	testCases := []struct {
+		desc     string
+		format   string
+		args     []string
+		expected []string
+		errorMsg string
+	}{
+		{
+			desc:     "server info",
+			format:   "text",
+			args:     []string{"serverinfo"},
+			expected: []string{"Cluster ID:   test-cluster", "Discovery:    _STAN.discover.test-cluster"},
+		},
+		{
+			desc:     "channels",
+			format:   "text",
+			args:     []string{"channels"},
+			expected: []string{"1   episodes      3         1      3     1000", "2   episodes.dlq  1         4      4     0         0          24h0m0s  false", "4   old           0"},
+		},
+		{
+			desc:     "channels as JSON",
+			format:   "json",
+			args:     []string{"channels"},
+			expected: []string{`"name": "raw",`, `"messages": 2,`, `"first_sequence": 5,`},
+		},
+		{
+			desc:     "subscriptions of channel",
+			format:   "text",
+			args:     []string{"subscriptions", "-channel", "episodes"},
+			expected: []string{"episodes  1   watcher-binge  binge", "30s"},
+		},
+		{
+			desc:     "clients as JSON",
+			format:   "json",
+			args:     []string{"clients"},
+			expected: []string{`"id": "neatflyx",`, `"hb_inbox": "_INBOX.hb1"`},
+		},
+		{
+			desc:     "messages",
+			format:   "text",
+			args:     []string{"messages", "-channel", "episodes", "-since", "2018-06-01T14:00:00Z"},
+			expected: []string{"episodes #2", "Type:       " + episodeType + " (v1)", `series_name:"Dark" season_no:1 episode_no:3`},
+		},
+		{
+			desc:     "messages as JSON",
+			format:   "json",
+			args:     []string{"messages", "-channel", "raw", "-channel-decoder", "raw=json"},
+			expected: []string{`"value": {`, `"hello": "world"`, `"error": "invalid JSON"`, `"raw": "ff00"`},
+		},
+		{
+			desc:     "unknown command",
+			format:   "text",
+			args:     []string{"topics"},
+			errorMsg: "unknown command 'topics'",
+		},
+		{
+			desc:     "unknown format",
+			format:   "yaml",
+			args:     []string{"channels"},
+			errorMsg: "unknown output format 'yaml'",
+		},
+		{
+			desc:     "invalid time",
+			format:   "text",
+			args:     []string{"messages", "-until", "yesterday"},
+			errorMsg: "invalid time 'yesterday'",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(spy, &out, tC.format, tC.args[0], tC.args[1:])
+			if tC.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tC.errorMsg) {
+					t.Errorf("Expected error '%s', got: %v", tC.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			for _, e := range tC.expected {
+				if !strings.Contains(out.String(), e) {
+					t.Errorf("Expected output to contain '%s', got:\n%s", e, out.String())
+				}
+			}
+		})
+	}
+}
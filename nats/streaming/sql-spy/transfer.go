@@ -0,0 +1,280 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/go-nats-streaming/pb"
+	"github.com/pkg/errors"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
+)
+
+// Connects to the target of imports, replaced in tests.
+var connect = streaming.Connect
+
+type exportSummary struct {
+	Channel       string `json:"channel"`
+	File          string `json:"file"`
+	Format        string `json:"format"`
+	Messages      int    `json:"messages"`
+	FirstSequence uint64 `json:"first_sequence"`
+	LastSequence  uint64 `json:"last_sequence"`
+}
+
+// Outcome of republishing a single exported message. Target sequence is
+// known for JetStream only, as NATS Streaming does not report it.
+type importedMessage struct {
+	SourceSequence uint64 `json:"source_sequence"`
+	Subject        string `json:"subject"`
+	GUID           string `json:"guid,omitempty"`
+	Stream         string `json:"stream,omitempty"`
+	TargetSequence uint64 `json:"target_sequence,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type importReport struct {
+	Published int               `json:"published"`
+	Failed    int               `json:"failed"`
+	Messages  []importedMessage `json:"messages"`
+}
+
+// Exports messages of a channel to a file, or to out if it is '-', in which
+// case there is no summary to write.
+func runExport(spy inspector, out io.Writer, fs *flag.FlagSet, args []string) (*exportSummary, error) {
+	var (
+		filter = filterFlags(fs)
+		format = fs.String("format", formatNDJSON, "format of the export, either ndjson or proto")
+		path   = fs.String("out", "-", "file to export messages to, '-' for standard output")
+	)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	f, err := filter()
+	if err != nil {
+		return nil, err
+	}
+	if f.Channel == "" {
+		return nil, errors.New("channel to export is required")
+	}
+
+	w := out
+	if *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create export file")
+		}
+		defer file.Close()
+		w = file
+	}
+	rw, err := newRecordWriter(*format, w)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := exportSummary{Channel: f.Channel, File: *path, Format: *format}
+	err = spy.EachMessage(f, func(msg storedMessage) error {
+		var msgProto pb.MsgProto
+		if err := msgProto.Unmarshal(msg.Data); err != nil {
+			return errors.Wrapf(err, "invalid message #%d", msg.Sequence)
+		}
+
+		subject := msgProto.Subject
+		if subject == "" {
+			subject = msg.Channel
+		}
+		if err := rw.Write(record{
+			Sequence:  msg.Sequence,
+			Timestamp: msg.Timestamp,
+			Subject:   subject,
+			Data:      msgProto.Data,
+		}); err != nil {
+			return errors.Wrapf(err, "failed to export message #%d", msg.Sequence)
+		}
+
+		if summary.Messages == 0 {
+			summary.FirstSequence = msg.Sequence
+		}
+		summary.LastSequence = msg.Sequence
+		summary.Messages++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, errors.Wrap(err, "failed to write export")
+	}
+
+	if *path == "-" {
+		return nil, nil
+	}
+	return &summary, nil
+}
+
+// Republishes exported messages, in their original order, reporting which
+// sequences they got in the target.
+func runImport(fs *flag.FlagSet, args []string) (*importReport, error) {
+	var (
+		path        = fs.String("in", "-", "file to import messages from, '-' for standard input")
+		format      = fs.String("format", formatNDJSON, "format of the export, either ndjson or proto")
+		backend     = fs.String("backend", streaming.BackendSTAN, "backend to publish to, either stan or jetstream")
+		url         = fs.String("url", "nats://localhost:4222", "URL of the target NATS server")
+		clusterID   = fs.String("cluster-id", "test-cluster", "NATS Streaming cluster ID")
+		clientID    = fs.String("client-id", "sql-spy", "client ID to connect with")
+		channelName = fs.String("channel", "", "channel (or subject) to publish to instead of the exported one")
+		stream      = fs.String("stream", "", "JetStream stream to create or update with imported subjects")
+		rate        = fs.Int("rate", 0, "publish at most this many messages per second, 0 for no limit")
+		maxInFlight = fs.Int("max-in-flight", 256, "publish at most this many messages without acknowledgement")
+	)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *rate < 0 {
+		return nil, errors.Errorf("invalid rate %d", *rate)
+	}
+	if *maxInFlight < 1 {
+		return nil, errors.Errorf("invalid max in flight %d", *maxInFlight)
+	}
+
+	r := io.Reader(os.Stdin)
+	if *path != "-" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open export file")
+		}
+		defer file.Close()
+		r = file
+	}
+	rr, err := newRecordReader(*format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := connect(streaming.Config{
+		Backend:   *backend,
+		URL:       *url,
+		ClusterID: *clusterID,
+		ClientID:  *clientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	imp := importer{
+		conn:     conn,
+		stream:   *stream,
+		subjects: make(map[string]bool),
+		inFlight: make(chan struct{}, *maxInFlight),
+		report:   &importReport{Messages: []importedMessage{}},
+	}
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+		imp.tick = ticker.C
+	}
+
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			imp.Wait()
+			return imp.report, errors.Wrapf(err, "failed to read record after %d messages", len(imp.report.Messages))
+		}
+		if *channelName != "" {
+			rec.Subject = *channelName
+		}
+		if err := imp.Publish(rec); err != nil {
+			imp.Wait()
+			return imp.report, err
+		}
+	}
+	imp.Wait()
+
+	if imp.report.Failed > 0 {
+		return imp.report, errors.Errorf("failed to import %d of %d messages", imp.report.Failed, len(imp.report.Messages))
+	}
+	return imp.report, nil
+}
+
+type importer struct {
+	conn     streaming.Conn
+	stream   string
+	subjects map[string]bool
+	tick     <-chan time.Time
+	inFlight chan struct{}
+	wg       sync.WaitGroup
+
+	// guards report, filled in by ack handlers
+	mu     sync.Mutex
+	report *importReport
+}
+
+// Publish sends the record without waiting for it to be stored. Only
+// failures to add subjects to the stream stop the import; failed messages
+// are reported.
+func (imp *importer) Publish(rec record) error {
+	if imp.stream != "" && !imp.subjects[rec.Subject] {
+		imp.subjects[rec.Subject] = true
+		var subjects []string
+		for s := range imp.subjects {
+			subjects = append(subjects, s)
+		}
+		sort.Strings(subjects)
+		if err := imp.conn.EnsureStream(imp.stream, subjects...); err != nil {
+			return err
+		}
+	}
+
+	if imp.tick != nil {
+		<-imp.tick
+	}
+	imp.inFlight <- struct{}{}
+	imp.wg.Add(1)
+
+	imp.mu.Lock()
+	i := len(imp.report.Messages)
+	imp.report.Messages = append(imp.report.Messages, importedMessage{
+		SourceSequence: rec.Sequence,
+		Subject:        rec.Subject,
+	})
+	imp.mu.Unlock()
+
+	_, err := imp.conn.PublishAsync(rec.Subject, rec.Data, func(ack streaming.PubAck, err error) {
+		imp.done(i, ack, err)
+	})
+	if err != nil {
+		imp.done(i, streaming.PubAck{}, err)
+	}
+	return nil
+}
+
+func (imp *importer) done(i int, ack streaming.PubAck, err error) {
+	defer imp.wg.Done()
+	defer func() { <-imp.inFlight }()
+
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	msg := &imp.report.Messages[i]
+	msg.GUID = ack.GUID
+	msg.Stream = ack.Stream
+	msg.TargetSequence = ack.Sequence
+	if err != nil {
+		msg.Error = err.Error()
+		imp.report.Failed++
+	} else {
+		imp.report.Published++
+	}
+}
+
+// Wait waits for all published messages to be acknowledged.
+func (imp *importer) Wait() {
+	imp.wg.Wait()
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mycodesmells/golang-examples/nats/messaging/streaming"
+)
+
+// Stores published messages, acknowledging them asynchronously like the
+// real backends do, unless they are set to fail.
+type fakeConn struct {
+	mu        sync.Mutex
+	published []record
+	streams   map[string][]string
+	failing   map[uint64]bool
+	closed    bool
+}
+
+func (c *fakeConn) Publish(subject string, data []byte) error {
+	return errors.New("not implemented")
+}
+
+func (c *fakeConn) PublishAsync(subject string, data []byte, handler streaming.PubAckHandler) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq := uint64(len(c.published) + 1)
+	guid := fmt.Sprintf("guid-%d", seq)
+	c.published = append(c.published, record{Sequence: seq, Subject: subject, Data: data})
+	go func() {
+		if c.failing[seq] {
+			handler(streaming.PubAck{GUID: guid}, errors.New("nats: timeout"))
+			return
+		}
+		handler(streaming.PubAck{GUID: guid, Stream: "BACKUP", Sequence: seq}, nil)
+	}()
+	return guid, nil
+}
+
+func (c *fakeConn) Subscribe(subject string, handler streaming.MsgHandler, opts streaming.SubscriptionOptions) (streaming.Subscription, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) EnsureStream(name string, subjects ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.streams[name] = subjects
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func useFakeConn(t *testing.T, failing ...uint64) (*fakeConn, func()) {
+	conn := &fakeConn{
+		streams: make(map[string][]string),
+		failing: make(map[uint64]bool),
+	}
+	for _, seq := range failing {
+		conn.failing[seq] = true
+	}
+
+	prev := connect
+	connect = func(cfg streaming.Config) (streaming.Conn, error) {
+		if cfg.Backend != streaming.BackendJetStream || cfg.ClientID != "sql-spy" {
+			t.Errorf("Unexpected connection config: %+v", cfg)
+		}
+		return conn, nil
+	}
+	return conn, func() { connect = prev }
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sql-spy")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestExportImport(t *testing.T) {
+	spy, cleanup := openFixture(t)
+	defer cleanup()
+	dir, cleanupDir := tempDir(t)
+	defer cleanupDir()
+
+	for _, format := range []string{formatNDJSON, formatProto} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(dir, "episodes."+format)
+
+			var out bytes.Buffer
+			err := run(spy, &out, "text", "export", []string{"-channel", "episodes", "-from-seq", "2", "-format", format, "-out", path})
+			if err != nil {
+				t.Fatalf("Failed to export: %v", err)
+			}
+			if expected := "Exported 2 messages of 'episodes' (#2-#3)"; !strings.Contains(out.String(), expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", expected, out.String())
+			}
+
+			conn, restore := useFakeConn(t)
+			defer restore()
+			out.Reset()
+			err = run(spy, &out, "json", "import", []string{"-backend", "jetstream", "-in", path, "-format", format, "-stream", "BACKUP"})
+			if err != nil {
+				t.Fatalf("Failed to import: %v", err)
+			}
+
+			expected := []record{
+				{Sequence: 1, Subject: "episodes", Data: envelope(t, episodeType, episode(2))},
+				{Sequence: 2, Subject: "episodes", Data: envelope(t, episodeType, episode(3))},
+			}
+			if !reflect.DeepEqual(conn.published, expected) {
+				t.Errorf("Expected published messages %v, got %v", expected, conn.published)
+			}
+			if subjects := conn.streams["BACKUP"]; !reflect.DeepEqual(subjects, []string{"episodes"}) {
+				t.Errorf("Expected stream with episodes subject, got %v", subjects)
+			}
+			if !conn.closed {
+				t.Error("Expected connection to be closed")
+			}
+
+			var report importReport
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			expectedReport := importReport{
+				Published: 2,
+				Messages: []importedMessage{
+					{SourceSequence: 2, Subject: "episodes", GUID: "guid-1", Stream: "BACKUP", TargetSequence: 1},
+					{SourceSequence: 3, Subject: "episodes", GUID: "guid-2", Stream: "BACKUP", TargetSequence: 2},
+				},
+			}
+			if !reflect.DeepEqual(report, expectedReport) {
+				t.Errorf("Expected report %+v, got %+v", expectedReport, report)
+			}
+		})
+	}
+}
+
+func TestExportToOutput(t *testing.T) {
+	spy, cleanup := openFixture(t)
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := run(spy, &out, "text", "export", []string{"-channel", "raw"}); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	// no summary mixed with the records
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got: %s", out.String())
+	}
+	var rec record
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	expected := record{
+		Sequence:  5,
+		Timestamp: stored.Add(5 * time.Hour),
+		Subject:   "raw",
+		Data:      []byte(`{"hello": "world"}`),
+	}
+	if !reflect.DeepEqual(rec, expected) {
+		t.Errorf("Expected record %+v, got %+v", expected, rec)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir, cleanupDir := tempDir(t)
+	defer cleanupDir()
+
+	path := filepath.Join(dir, "export.ndjson")
+	var lines []string
+	for seq := 10; seq < 13; seq++ {
+		bs, _ := json.Marshal(record{Sequence: uint64(seq), Subject: "episodes", Data: []byte{byte(seq)}})
+		lines = append(lines, string(bs))
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	brokenPath := filepath.Join(dir, "broken.ndjson")
+	if err := ioutil.WriteFile(brokenPath, []byte(lines[0]+"\nnot JSON"), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	testCases := []struct {
+		desc      string
+		args      []string
+		failing   []uint64
+		subjects  []string
+		failed    []uint64
+		errorMsg  string
+		minimumIn time.Duration
+	}{
+		{
+			desc:     "all published",
+			subjects: []string{"episodes", "episodes", "episodes"},
+		},
+		{
+			desc:     "another channel",
+			args:     []string{"-channel", "episodes.restored"},
+			subjects: []string{"episodes.restored", "episodes.restored", "episodes.restored"},
+		},
+		{
+			desc:     "failed acknowledgement",
+			failing:  []uint64{2},
+			subjects: []string{"episodes", "episodes", "episodes"},
+			failed:   []uint64{11},
+			errorMsg: "failed to import 1 of 3 messages",
+		},
+		{
+			desc:      "rate limited",
+			args:      []string{"-rate", "20", "-max-in-flight", "1"},
+			subjects:  []string{"episodes", "episodes", "episodes"},
+			minimumIn: 150 * time.Millisecond,
+		},
+		{
+			desc:     "invalid format",
+			args:     []string{"-format", "csv"},
+			errorMsg: "unknown export format 'csv'",
+		},
+		{
+			desc:     "invalid record",
+			args:     []string{"-in", brokenPath},
+			subjects: []string{"episodes"},
+			errorMsg: "failed to read record after 1 messages",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			conn, restore := useFakeConn(t, tC.failing...)
+			defer restore()
+
+			start := time.Now()
+			args := append([]string{"-backend", "jetstream", "-in", path}, tC.args...)
+			var out bytes.Buffer
+			err := run(inspector{}, &out, "json", "import", args)
+			if tC.errorMsg == "" && err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if tC.errorMsg != "" && (err == nil || !strings.Contains(err.Error(), tC.errorMsg)) {
+				t.Errorf("Expected error '%s', got: %v", tC.errorMsg, err)
+			}
+			if elapsed := time.Since(start); elapsed < tC.minimumIn {
+				t.Errorf("Expected import to take at least %v, took %v", tC.minimumIn, elapsed)
+			}
+
+			var subjects []string
+			for _, rec := range conn.published {
+				subjects = append(subjects, rec.Subject)
+			}
+			if !reflect.DeepEqual(subjects, tC.subjects) {
+				t.Errorf("Expected messages published on %v, got %v", tC.subjects, subjects)
+			}
+
+			if out.Len() == 0 {
+				return
+			}
+			var report importReport
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			var failed []uint64
+			for _, m := range report.Messages {
+				if m.Error != "" {
+					failed = append(failed, m.SourceSequence)
+				}
+			}
+			if !reflect.DeepEqual(failed, tC.failed) {
+				t.Errorf("Expected messages %v to fail, got %v", tC.failed, failed)
+			}
+			if report.Published+report.Failed != len(report.Messages) {
+				t.Errorf("Expected every message to be acknowledged, got %+v", report)
+			}
+		})
+	}
+}
+
+func TestProtoRecordsRoundTrip(t *testing.T) {
+	records := []record{
+		{Sequence: 1, Timestamp: stored, Subject: "episodes", Data: []byte("first")},
+		{Sequence: 7, Timestamp: stored.Add(time.Minute), Subject: "episodes.dlq", Data: bytes.Repeat([]byte{0xff}, 300)},
+	}
+
+	var buf bytes.Buffer
+	w, err := newRecordWriter(formatProto, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for _, rec := range records {
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("Failed to write record: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Failed to flush records: %v", err)
+	}
+
+	// truncated record is reported rather than silently dropped
+	buf.Write([]byte{0x05, 0x08})
+
+	r, err := newRecordReader(formatProto, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	for _, expected := range records {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("Failed to read record: %v", err)
+		}
+		if !reflect.DeepEqual(rec, expected) {
+			t.Errorf("Expected record %+v, got %+v", expected, rec)
+		}
+	}
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "truncated record") {
+		t.Errorf("Expected truncated record error, got: %v", err)
+	}
+}